@@ -0,0 +1,314 @@
+package quote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dividend - cash dividend per share, dated by its ex date
+type Dividend struct {
+	Date   time.Time `json:"date"`
+	Amount float64   `json:"amount"`
+}
+
+// Split - stock split, Ratio is new shares per old share, e.g. 4 for
+// a 4:1 split and 0.1 for a 1:10 reverse split
+type Split struct {
+	Date  time.Time `json:"date"`
+	Ratio float64   `json:"ratio"`
+}
+
+// CorporateActions - dividends and splits of a symbol, oldest first
+type CorporateActions struct {
+	Symbol    string     `json:"symbol"`
+	Dividends []Dividend `json:"dividends"`
+	Splits    []Split    `json:"splits"`
+}
+
+// Events - kinds of corporate action to download
+type Events int
+
+const (
+	// Dividends - cash dividends
+	Dividends Events = 1 << iota
+	// Splits - stock splits
+	Splits
+	// AllEvents - dividends and splits
+	AllEvents = Dividends | Splits
+)
+
+// ParseEvents - Events from a comma separated list of div, split or all
+func ParseEvents(s string) (Events, error) {
+	var events Events
+	for _, name := range strings.Split(s, ",") {
+		switch strings.TrimSpace(name) {
+		case "div", "dividend", "dividends":
+			events |= Dividends
+		case "split", "splits":
+			events |= Splits
+		case "all":
+			events |= AllEvents
+		default:
+			return 0, fmt.Errorf("invalid event '%s', must be div, split or all", name)
+		}
+	}
+	return events, nil
+}
+
+// Actions - dividends and splits of symbol between from and to from
+// the named source, which must be yahoo or tiingo
+func (c *Client) Actions(ctx context.Context, source, symbol string, from, to time.Time, events Events) (CorporateActions, error) {
+	switch source {
+	case "yahoo":
+		return c.ActionsFromYahoo(ctx, symbol, from, to, events)
+	case "tiingo":
+		return c.ActionsFromTiingo(ctx, symbol, from, to, events)
+	}
+	return CorporateActions{}, fmt.Errorf("corporate actions not supported by %s", source)
+}
+
+// ActionsSupported - report whether the named source has corporate actions
+func ActionsSupported(source string) bool {
+	return source == "yahoo" || source == "tiingo"
+}
+
+// NewActionsFromYahoo - Yahoo dividends and splits for a symbol
+func NewActionsFromYahoo(symbol, startDate, endDate string, events Events) (CorporateActions, error) {
+	return NewActionsFromYahooContext(context.Background(), symbol, startDate, endDate, events)
+}
+
+// NewActionsFromYahooContext - NewActionsFromYahoo with a context for cancellation
+func NewActionsFromYahooContext(ctx context.Context, symbol, startDate, endDate string, events Events) (CorporateActions, error) {
+	from, to, err := parseDates(startDate, endDate)
+	if err != nil {
+		return CorporateActions{}, err
+	}
+	return defaultClient().ActionsFromYahoo(ctx, symbol, from, to, events)
+}
+
+// ActionsFromYahoo - Yahoo dividends and splits for a symbol. The
+// dividends are restated in shares after the latest split, the basis of
+// Yahoo's split adjusted prices, so Adjust Yahoo bars without the splits.
+func (c *Client) ActionsFromYahoo(ctx context.Context, symbol string, from, to time.Time, events Events) (CorporateActions, error) {
+	actions := CorporateActions{Symbol: symbol}
+
+	if events&Dividends != 0 {
+		// Date,Dividends
+		rows, err := c.yahooCSV(ctx, symbol, from, to, "div", 2)
+		if err != nil {
+			return CorporateActions{}, err
+		}
+		for _, row := range skipHeader(rows) {
+			d, err := sessionDate("yahoo", row[0])
+			if err != nil {
+				return CorporateActions{}, malformed("yahoo", symbol, err)
+			}
+			amount, err := strconv.ParseFloat(row[1], 64)
+			if err != nil {
+				return CorporateActions{}, malformed("yahoo", symbol, err)
+			}
+			actions.Dividends = append(actions.Dividends, Dividend{Date: d, Amount: amount})
+		}
+	}
+
+	if events&Splits != 0 {
+		// Date,Stock Splits with ratios such as 4:1
+		rows, err := c.yahooCSV(ctx, symbol, from, to, "split", 2)
+		if err != nil {
+			return CorporateActions{}, err
+		}
+		for _, row := range skipHeader(rows) {
+			d, err := sessionDate("yahoo", row[0])
+			if err != nil {
+				return CorporateActions{}, malformed("yahoo", symbol, err)
+			}
+			ratio, err := parseSplitRatio(row[1])
+			if err != nil {
+				return CorporateActions{}, malformed("yahoo", symbol, err)
+			}
+			actions.Splits = append(actions.Splits, Split{Date: d, Ratio: ratio})
+		}
+	}
+
+	actions.sort()
+	return actions, nil
+}
+
+// NewActionsFromTiingo - Tiingo dividends and splits for a symbol
+func NewActionsFromTiingo(symbol, startDate, endDate string, token string, events Events) (CorporateActions, error) {
+	return NewActionsFromTiingoContext(context.Background(), symbol, startDate, endDate, token, events)
+}
+
+// NewActionsFromTiingoContext - NewActionsFromTiingo with a context for cancellation
+func NewActionsFromTiingoContext(ctx context.Context, symbol, startDate, endDate string, token string, events Events) (CorporateActions, error) {
+	from, to, err := parseDates(startDate, endDate)
+	if err != nil {
+		return CorporateActions{}, err
+	}
+	c := defaultClient()
+	c.TiingoToken = token
+	return c.ActionsFromTiingo(ctx, symbol, from, to, events)
+}
+
+// ActionsFromTiingo - Tiingo dividends and splits for a symbol, taken
+// from the divCash and splitFactor of its daily prices
+func (c *Client) ActionsFromTiingo(ctx context.Context, symbol string, from, to time.Time, events Events) (CorporateActions, error) {
+	tiingo, err := c.tiingoPrices(ctx, symbol, from, to)
+	if err != nil {
+		return CorporateActions{}, err
+	}
+
+	actions := CorporateActions{Symbol: symbol}
+	for _, p := range tiingo {
+		if p.DivCash == 0 && (p.SplitFactor == 0 || p.SplitFactor == 1) {
+			continue
+		}
+		d, err := p.date()
+		if err != nil {
+			return CorporateActions{}, malformed("tiingo", symbol, err)
+		}
+		if events&Dividends != 0 && p.DivCash != 0 {
+			actions.Dividends = append(actions.Dividends, Dividend{Date: d, Amount: p.DivCash})
+		}
+		if events&Splits != 0 && p.SplitFactor != 0 && p.SplitFactor != 1 {
+			actions.Splits = append(actions.Splits, Split{Date: d, Ratio: p.SplitFactor})
+		}
+	}
+	return actions, nil
+}
+
+// skipHeader - rows without the first
+func skipHeader(rows [][]string) [][]string {
+	if len(rows) == 0 {
+		return rows
+	}
+	return rows[1:]
+}
+
+// parseSplitRatio - new shares per old share from "4:1" or "4/1"
+func parseSplitRatio(s string) (float64, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == '/' })
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("bad split ratio '%s'", s)
+	}
+	f, err := parseFloats(parts...)
+	if err != nil {
+		return 0, err
+	}
+	if f[0] <= 0 || f[1] <= 0 {
+		return 0, fmt.Errorf("bad split ratio '%s'", s)
+	}
+	return f[0] / f[1], nil
+}
+
+// sort - order the events by date, yahoo doesn't
+func (a *CorporateActions) sort() {
+	sort.SliceStable(a.Dividends, func(i, j int) bool { return a.Dividends[i].Date.Before(a.Dividends[j].Date) })
+	sort.SliceStable(a.Splits, func(i, j int) bool { return a.Splits[i].Date.Before(a.Splits[j].Date) })
+}
+
+// CSV - convert CorporateActions to a csv string with a row per event
+func (a CorporateActions) CSV() string {
+	type row struct {
+		date  time.Time
+		event string
+		value string
+	}
+	var rows []row
+	for _, d := range a.Dividends {
+		rows = append(rows, row{d.Date, "div", strconv.FormatFloat(d.Amount, 'f', -1, 64)})
+	}
+	for _, s := range a.Splits {
+		rows = append(rows, row{s.Date, "split", strconv.FormatFloat(s.Ratio, 'f', -1, 64)})
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].date.Before(rows[j].date) })
+
+	var buffer bytes.Buffer
+	buffer.WriteString("symbol,date,event,value\n")
+	for _, r := range rows {
+		buffer.WriteString(fmt.Sprintf("%s,%s,%s,%s\n", a.Symbol, r.date.Format(csvDateFormat), r.event, r.value))
+	}
+	return buffer.String()
+}
+
+// WriteCSV - write CorporateActions to a csv file
+func (a CorporateActions) WriteCSV(filename string) error {
+	if filename == "" {
+		filename = a.Symbol + "-events.csv"
+	}
+	return ioutil.WriteFile(filename, []byte(a.CSV()), 0644)
+}
+
+// JSON - convert CorporateActions to a json string
+func (a CorporateActions) JSON(indent bool) string {
+	var j []byte
+	if indent {
+		j, _ = json.MarshalIndent(a, "", "  ")
+	} else {
+		j, _ = json.Marshal(a)
+	}
+	return string(j)
+}
+
+// WriteJSON - write CorporateActions to a json file
+func (a CorporateActions) WriteJSON(filename string, indent bool) error {
+	if filename == "" {
+		filename = a.Symbol + "-events.json"
+	}
+	return ioutil.WriteFile(filename, []byte(a.JSON(indent)), 0644)
+}
+
+// NewActionsFromCSV - parse a csv string written by CorporateActions.CSV
+func NewActionsFromCSV(csv string) (CorporateActions, error) {
+	var a CorporateActions
+	rows := strings.Split(csv, "\n")
+	for row := 1; row < len(rows); row++ {
+		if strings.TrimSpace(rows[row]) == "" {
+			continue
+		}
+		line := strings.Split(strings.TrimRight(rows[row], "\r"), ",")
+		if len(line) != 4 {
+			return CorporateActions{}, fmt.Errorf("csv row %d: expected 4 fields, got %d", row+1, len(line))
+		}
+		a.Symbol = line[0]
+		d, err := parseCSVDate("", line[1])
+		if err != nil {
+			return CorporateActions{}, fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		v, err := strconv.ParseFloat(line[3], 64)
+		if err != nil {
+			return CorporateActions{}, fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		switch line[2] {
+		case "div":
+			a.Dividends = append(a.Dividends, Dividend{Date: d, Amount: v})
+		case "split":
+			a.Splits = append(a.Splits, Split{Date: d, Ratio: v})
+		default:
+			return CorporateActions{}, fmt.Errorf("csv row %d: unknown event '%s'", row+1, line[2])
+		}
+	}
+	return a, nil
+}
+
+// NewActionsFromJSON - parse a json string written by CorporateActions.JSON
+func NewActionsFromJSON(jsn string) (CorporateActions, error) {
+	var a CorporateActions
+	if err := json.Unmarshal([]byte(jsn), &a); err != nil {
+		return a, err
+	}
+	for i := range a.Dividends {
+		a.Dividends[i].Date = a.Dividends[i].Date.UTC()
+	}
+	for i := range a.Splits {
+		a.Splits[i].Date = a.Splits[i].Date.UTC()
+	}
+	return a, nil
+}
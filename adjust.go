@@ -0,0 +1,147 @@
+package quote
+
+import (
+	"fmt"
+	"time"
+)
+
+// Adjustment - how Adjust rewrites the history of a raw Quote
+type Adjustment int
+
+const (
+	// AdjustSplits - prices before each split are divided by its ratio and
+	// volumes multiplied by it, so the last bar is unchanged
+	AdjustSplits Adjustment = iota
+	// AdjustDividends - AdjustSplits, with prices before each ex date also
+	// scaled by 1 - dividend / previous close, as in Yahoo's adjusted close
+	AdjustDividends
+	// AdjustTotalReturn - the value of holding the first bar's share with
+	// dividends reinvested on their ex dates, so the first bar is unchanged
+	// and volumes are in first bar shares
+	AdjustTotalReturn
+)
+
+func (a Adjustment) String() string {
+	switch a {
+	case AdjustSplits:
+		return "splits"
+	case AdjustDividends:
+		return "dividends"
+	case AdjustTotalReturn:
+		return "total"
+	}
+	return fmt.Sprintf("Adjustment(%d)", int(a))
+}
+
+// ParseAdjustment - Adjustment from "splits", "dividends" or "total"
+func ParseAdjustment(s string) (Adjustment, error) {
+	switch s {
+	case "splits", "split":
+		return AdjustSplits, nil
+	case "dividends", "div":
+		return AdjustDividends, nil
+	case "total":
+		return AdjustTotalReturn, nil
+	}
+	return AdjustSplits, fmt.Errorf("invalid adjustment '%s', must be splits, dividends or total", s)
+}
+
+// Adjust - q, which must hold raw prices, adjusted for the splits and
+// dividends in actions. Dividend amounts are per share on their ex date,
+// as Tiingo reports them. Yahoo's prices are already split adjusted and
+// its dividends restated in today's shares, so adjust Yahoo bars with
+// the dividends alone. Events outside q's bars are ignored. The optional
+// columns, including the source's own adjusted prices, are copied
+// unchanged. q is not modified.
+func Adjust(q Quote, actions CorporateActions, mode Adjustment) (Quote, error) {
+	if actions.Symbol != "" && q.Symbol != "" && actions.Symbol != q.Symbol {
+		return Quote{}, fmt.Errorf("adjust %s with corporate actions of %s", q.Symbol, actions.Symbol)
+	}
+	n := len(q.Date)
+
+	// ratio[k] - product of the splits taking effect on bar k
+	// div[k] - dividends going ex on bar k
+	ratio := make([]float64, n)
+	div := make([]float64, n)
+	for k := range ratio {
+		ratio[k] = 1
+	}
+	for _, s := range actions.Splits {
+		if s.Ratio <= 0 {
+			return Quote{}, fmt.Errorf("%s split on %s has ratio %g", q.Symbol, s.Date.Format("2006-01-02"), s.Ratio)
+		}
+		if k := exBar(q, s.Date); k > 0 && k < n {
+			ratio[k] *= s.Ratio
+		}
+	}
+	for _, d := range actions.Dividends {
+		if k := exBar(q, d.Date); k > 0 && k < n {
+			div[k] += d.Amount
+		}
+	}
+
+	// walk back from the last bar accumulating the price and volume
+	// multipliers of every event after each bar
+	price := make([]float64, n)
+	volume := make([]float64, n)
+	p, v := 1.0, 1.0
+	for i := n - 1; i >= 0; i-- {
+		price[i], volume[i] = p, v
+		if i == 0 {
+			break
+		}
+		p /= ratio[i]
+		v *= ratio[i]
+		if div[i] != 0 && mode != AdjustSplits {
+			// previous close in the share basis of the ex date
+			prev := q.Close[i-1] / ratio[i]
+			if prev <= div[i] {
+				return Quote{}, fmt.Errorf("%s dividend of %g on %s is not below the previous close %g",
+					q.Symbol, div[i], q.Date[i].Format("2006-01-02"), prev)
+			}
+			p *= 1 - div[i]/prev
+		}
+	}
+
+	if mode == AdjustTotalReturn && n > 0 {
+		p0, v0 := price[0], volume[0]
+		for i := range price {
+			price[i] /= p0
+			volume[i] /= v0
+		}
+	}
+
+	adj := NewQuote(q.Symbol, n)
+	adj.Precision = q.Precision
+	adj.VolumePrecision = q.VolumePrecision
+	adj.BarTime = q.BarTime
+	copy(adj.Date, q.Date)
+	for i, col := range q.extras() {
+		if *col != nil {
+			*adj.extras()[i] = append([]float64(nil), *col...)
+		}
+	}
+	for i := 0; i < n; i++ {
+		adj.Open[i] = q.Open[i] * price[i]
+		adj.High[i] = q.High[i] * price[i]
+		adj.Low[i] = q.Low[i] * price[i]
+		adj.Close[i] = q.Close[i] * price[i]
+		adj.Volume[i] = q.Volume[i] * volume[i]
+	}
+	return adj, nil
+}
+
+// exBar - index of the first bar of q trading on or after the ex date t,
+// which is midnight of the day, len(q.Date) if there is none
+func exBar(q Quote, t time.Time) int {
+	for k, d := range q.Date {
+		if q.BarTime == BarClose {
+			if d.After(t) {
+				return k
+			}
+		} else if !d.Before(t) {
+			return k
+		}
+	}
+	return len(q.Date)
+}
@@ -0,0 +1,214 @@
+package quote
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Join - which dates Align puts on the common timeline
+type Join int
+
+const (
+	// InnerJoin - dates every quote has a bar on
+	InnerJoin Join = iota
+	// OuterJoin - dates any quote has a bar on
+	OuterJoin
+	// ReferenceJoin - dates the AlignOptions.Reference quote has a bar on
+	ReferenceJoin
+)
+
+// Fill - what Align puts in place of a missing bar
+type Fill int
+
+const (
+	// FillForward - the close of the quote's latest earlier bar, on the
+	// timeline or not, as open, high, low and close, with zero volume.
+	// Bars before a quote's first are NaN.
+	FillForward Fill = iota
+	// FillNaN - NaN prices and volume
+	FillNaN
+	// FillDrop - leave out the dates any quote is missing
+	FillDrop
+)
+
+// AlignOptions - how Align builds the common timeline
+type AlignOptions struct {
+	Join      Join
+	Reference string // symbol whose dates ReferenceJoin uses
+	Fill      Fill
+}
+
+// Align - q with every quote on one timeline, and that timeline. Each
+// aligned quote has a bar on every date of the timeline, filled as opts
+// says where it had none, and only those bars. The optional columns are
+// filled like the prices and volume. Exact is kept where every filled
+// bar can be written exactly. q is not modified.
+func (q Quotes) Align(opts AlignOptions) (Quotes, []time.Time, error) {
+	if len(q) == 0 {
+		return Quotes{}, nil, nil
+	}
+	for _, quote := range q[1:] {
+		if quote.Len() > 0 && q[0].Len() > 0 && quote.BarTime != q[0].BarTime {
+			return nil, nil, fmt.Errorf("align %s bars stamped with their %s time with %s bars stamped with their %s time",
+				q[0].Symbol, q[0].BarTime, quote.Symbol, quote.BarTime)
+		}
+	}
+
+	// index of each quote's bars by date
+	bars := make([]map[int64]int, len(q))
+	count := make(map[int64]int)
+	dates := make(map[int64]time.Time)
+	for n, quote := range q {
+		bars[n] = make(map[int64]int, quote.Len())
+		for i, d := range quote.Date {
+			key := d.UnixNano()
+			if _, dup := bars[n][key]; dup {
+				return nil, nil, fmt.Errorf("align %s: duplicate bars at %s", quote.Symbol, d.Format(csvDateFormat))
+			}
+			bars[n][key] = i
+			count[key]++
+			dates[key] = d
+		}
+	}
+
+	var keys []int64
+	switch opts.Join {
+	case InnerJoin:
+		for key, c := range count {
+			if c == len(q) {
+				keys = append(keys, key)
+			}
+		}
+	case OuterJoin:
+		for key := range count {
+			keys = append(keys, key)
+		}
+	case ReferenceJoin:
+		ref := -1
+		for n, quote := range q {
+			if quote.Symbol == opts.Reference {
+				ref = n
+				break
+			}
+		}
+		if ref < 0 {
+			return nil, nil, fmt.Errorf("align: reference symbol '%s' not found", opts.Reference)
+		}
+		for key := range bars[ref] {
+			keys = append(keys, key)
+		}
+	default:
+		return nil, nil, fmt.Errorf("align: unknown join %d", opts.Join)
+	}
+	if opts.Fill == FillDrop {
+		kept := keys[:0]
+		for _, key := range keys {
+			if count[key] == len(q) {
+				kept = append(kept, key)
+			}
+		}
+		keys = kept
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	timeline := make([]time.Time, len(keys))
+	for k, key := range keys {
+		timeline[k] = dates[key]
+	}
+	aligned := make(Quotes, len(q))
+	for n, quote := range q {
+		idx := make([]int, len(keys))
+		for k, key := range keys {
+			if i, ok := bars[n][key]; ok {
+				idx[k] = i
+			} else {
+				idx[k] = -1
+			}
+		}
+		aligned[n] = quote.align(timeline, idx, opts.Fill)
+	}
+	return aligned, timeline, nil
+}
+
+// align - q's bars idx on dates, filling the ones that are -1
+func (q Quote) align(dates []time.Time, idx []int, fill Fill) Quote {
+	p := NewQuote(q.Symbol, len(dates))
+	p.Precision = q.Precision
+	p.VolumePrecision = q.VolumePrecision
+	p.BarTime = q.BarTime
+	copy(p.Date, dates)
+	cols, pcols := q.extras(), p.extras()
+	for c, col := range cols {
+		if *col != nil {
+			*pcols[c] = make([]float64, len(dates))
+		}
+	}
+	if q.Exact != nil {
+		p.Exact = make([]ExactBar, len(dates))
+	}
+
+	// q's bars in date order, walked alongside the timeline so prev is
+	// the latest bar at or before each date
+	order := make([]int, q.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return q.Date[order[i]].Before(q.Date[order[j]]) })
+
+	nan := math.NaN()
+	next, prev := 0, -1
+	for k, i := range idx {
+		for next < len(order) && !q.Date[order[next]].After(dates[k]) {
+			prev = order[next]
+			next++
+		}
+		if i >= 0 {
+			p.Open[k], p.High[k], p.Low[k], p.Close[k], p.Volume[k] = q.Open[i], q.High[i], q.Low[i], q.Close[i], q.Volume[i]
+			for c, col := range cols {
+				if *col != nil {
+					(*pcols[c])[k] = (*col)[i]
+				}
+			}
+			if p.Exact != nil {
+				p.Exact[k] = q.Exact[i]
+			}
+			continue
+		}
+
+		forward := fill == FillForward && prev >= 0
+		price, volume := nan, nan
+		if forward {
+			price, volume = q.Close[prev], 0
+		}
+		p.Open[k], p.High[k], p.Low[k], p.Close[k], p.Volume[k] = price, price, price, price, volume
+		for c, col := range cols {
+			if *col == nil {
+				continue
+			}
+			v := nan
+			if forward {
+				switch extraColumns[c].name {
+				case "adjopen", "adjhigh", "adjlow", "adjclose":
+					v = (*col)[prev]
+					if q.AdjClose != nil {
+						v = q.AdjClose[prev]
+					}
+				default:
+					v = 0
+				}
+			}
+			(*pcols[c])[k] = v
+		}
+		if p.Exact != nil {
+			if forward {
+				c := q.Exact[prev].Close
+				p.Exact[k] = ExactBar{c, c, c, c, "0"}
+			} else {
+				p.Exact = nil
+			}
+		}
+	}
+	return p
+}
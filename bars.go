@@ -0,0 +1,130 @@
+package quote
+
+import (
+	"sort"
+	"time"
+)
+
+// Bar - one row of a Quote
+type Bar struct {
+	Date   time.Time `json:"date"`
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// Len - number of bars in q
+func (q Quote) Len() int {
+	return len(q.Date)
+}
+
+// Slice - bars i up to but not including j, with every column cut the
+// same way. Like a slice expression it shares q's arrays and panics if
+// the indexes are out of range.
+func (q Quote) Slice(i, j int) Quote {
+	q.Date = q.Date[i:j]
+	q.Open = q.Open[i:j]
+	q.High = q.High[i:j]
+	q.Low = q.Low[i:j]
+	q.Close = q.Close[i:j]
+	q.Volume = q.Volume[i:j]
+	if q.Exact != nil {
+		q.Exact = q.Exact[i:j]
+	}
+	for _, col := range q.extras() {
+		if *col != nil {
+			*col = (*col)[i:j]
+		}
+	}
+	return q
+}
+
+// Last - the last n bars of q, all of them if it has fewer
+func (q Quote) Last(n int) Quote {
+	if n > q.Len() {
+		n = q.Len()
+	}
+	if n < 0 {
+		n = 0
+	}
+	return q.Slice(q.Len()-n, q.Len())
+}
+
+// search - index of the first bar at or after t
+func (q Quote) search(t time.Time) int {
+	return sort.Search(len(q.Date), func(i int) bool { return !q.Date[i].Before(t) })
+}
+
+// Between - the bars of q from from to to, both included. The dates
+// must be in ascending order, as they are in every Quote from this
+// package.
+func (q Quote) Between(from, to time.Time) Quote {
+	i := q.search(from)
+	j := i + sort.Search(len(q.Date)-i, func(k int) bool { return q.Date[i+k].After(to) })
+	return q.Slice(i, j)
+}
+
+// IndexOf - index of the bar dated t, -1 if there is none
+func (q Quote) IndexOf(t time.Time) int {
+	i := q.search(t)
+	if i < len(q.Date) && q.Date[i].Equal(t) {
+		return i
+	}
+	return -1
+}
+
+// At - the bar dated t, false if there is none
+func (q Quote) At(t time.Time) (Bar, bool) {
+	i := q.IndexOf(t)
+	if i < 0 {
+		return Bar{}, false
+	}
+	return q.Bar(i), true
+}
+
+// Bar - bar i of q
+func (q Quote) Bar(i int) Bar {
+	return Bar{
+		Date:   q.Date[i],
+		Open:   q.Open[i],
+		High:   q.High[i],
+		Low:    q.Low[i],
+		Close:  q.Close[i],
+		Volume: q.Volume[i],
+	}
+}
+
+// BarIterator - steps through the bars of a Quote, oldest first:
+//
+//	for it := q.Bars(); it.Next(); {
+//		b := it.Bar()
+//	}
+type BarIterator struct {
+	q Quote
+	i int
+}
+
+// Bars - an iterator over the bars of q
+func (q Quote) Bars() *BarIterator {
+	return &BarIterator{q: q, i: -1}
+}
+
+// Next - advance to the next bar, false when there are no more
+func (it *BarIterator) Next() bool {
+	if it.i < it.q.Len() {
+		it.i++
+	}
+	return it.i < it.q.Len()
+}
+
+// Index - index in the Quote of the current bar
+func (it *BarIterator) Index() int {
+	return it.i
+}
+
+// Bar - the current bar
+func (it *BarIterator) Bar() Bar {
+	return it.q.Bar(it.i)
+}
@@ -0,0 +1,86 @@
+package quote
+
+import (
+	"fmt"
+	"time"
+)
+
+// BarTime - which end of its interval a bar's timestamp marks
+type BarTime int
+
+const (
+	// BarOpen - bars are stamped with the start of their interval, the
+	// default for every source
+	BarOpen BarTime = iota
+	// BarClose - bars are stamped with the end of their interval
+	BarClose
+)
+
+func (b BarTime) String() string {
+	if b == BarClose {
+		return "close"
+	}
+	return "open"
+}
+
+// ParseBarTime - BarTime from "open" or "close"
+func ParseBarTime(s string) (BarTime, error) {
+	switch s {
+	case "open":
+		return BarOpen, nil
+	case "close":
+		return BarClose, nil
+	}
+	return BarOpen, fmt.Errorf("invalid bar time '%s', must be open or close", s)
+}
+
+// MarshalText - the name of b, for json
+func (b BarTime) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText - parse the name written by MarshalText
+func (b *BarTime) UnmarshalText(text []byte) error {
+	var err error
+	*b, err = ParseBarTime(string(text))
+	return err
+}
+
+// barEnd - the end of the bar of period that starts at t
+func barEnd(t time.Time, period Period) time.Time {
+	if canonical(period) == Monthly {
+		return t.AddDate(0, 1, 0)
+	}
+	return t.Add(period.Duration())
+}
+
+// stampSession - q, whose daily bars of source are stamped with the
+// start of their session date, in the c.BarTime convention. BarClose
+// stamps each bar with the session close of the exchange.
+func (c *Client) stampSession(q Quote, source string) Quote {
+	end, ok := exchangeCloses[source]
+	if !ok {
+		return c.stamp(q, Daily)
+	}
+	if c.BarTime != BarClose || q.BarTime == BarClose {
+		return q
+	}
+	for i, d := range q.Date {
+		q.Date[i] = d.Add(end)
+	}
+	q.BarTime = BarClose
+	return q
+}
+
+// stamp - q, whose bars of period are stamped with their open time, in
+// the c.BarTime convention
+func (c *Client) stamp(q Quote, period Period) Quote {
+	if c.BarTime != BarClose || q.BarTime == BarClose {
+		return q
+	}
+	for i, d := range q.Date {
+		q.Date[i] = barEnd(d, period)
+	}
+	q.BarTime = BarClose
+	return q
+}
@@ -0,0 +1,65 @@
+package quote
+
+import (
+	"time"
+)
+
+// Client - settings for downloading quotes. Create one with NewClient
+// and don't change it once it is in use; the methods are then safe to
+// call from multiple goroutines.
+type Client struct {
+	Log         Logger        // diagnostics, nil to discard them
+	Delay       time.Duration // pause between symbols in batch downloads
+	HTTP        HTTPConfig    // http client, timeout, base urls and retries
+	TiingoToken string        // api token for the tiingo sources
+	BarTime     BarTime       // stamp bars with their open (default) or close time
+	Precision   int64         // decimal places of every quote's prices, 0 for the source's, NoDecimals for none
+	Exact       bool          // keep the exchange's decimals in Quote.Exact, binance and coinbase
+
+	limits     *rateLimits
+	precisions *precisionCache
+}
+
+// NewClient - client with the default settings and its own rate limits
+func NewClient() *Client {
+	return &Client{
+		Delay:      100 * time.Millisecond,
+		HTTP:       HTTPConfig{Retry: DefaultRetryPolicy},
+		limits:     &rateLimits{},
+		precisions: &precisionCache{},
+	}
+}
+
+// defaultClient - client for the package level functions, built from
+// the deprecated package level settings each time so changes to them
+// still take effect
+func defaultClient() *Client {
+	return &Client{
+		Log:        NewStdLogger(Log, LevelInfo),
+		Delay:      Delay * time.Millisecond,
+		HTTP:       HTTP,
+		limits:     defaultRateLimits,
+		precisions: defaultPrecisions,
+	}
+}
+
+// SetRateLimit - limit http requests to the named source, pages and
+// retries included, to perSecond on average with bursts of up to burst
+func (c *Client) SetRateLimit(source string, perSecond float64, burst int) {
+	c.rateLimits().set(source, NewRateLimiter(perSecond, burst))
+}
+
+// RateLimit - the limiter for the named source, created on first use
+// with one request per c.Delay and rebuilt if c.Delay changes, unless
+// it was set with SetRateLimit
+func (c *Client) RateLimit(source string) *RateLimiter {
+	return c.rateLimits().get(source, c.Delay)
+}
+
+func (c *Client) rateLimits() *rateLimits {
+	if c.limits == nil {
+		// a Client not made by NewClient shares the package limits
+		return defaultRateLimits
+	}
+	return c.limits
+}
@@ -0,0 +1,149 @@
+package quote
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts - absolute formats ParseDate tries, in order
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04Z07:00",
+	"2006-01-02 15:04 Z07:00",
+	"2006-01-02 15:04 -0700",
+	"2006-1-2T15:04:05",
+	"2006-1-2T15:04",
+	"2006-1-2 15:04:05",
+	"2006-1-2 15:04",
+	"2006-1-2",
+	"2006-1",
+	"2006",
+	"20060102",
+}
+
+// ParseDate - parse a date expression, relative to the current time in
+// UTC. See ParseDateAt for the accepted forms.
+func ParseDate(s string) (time.Time, error) {
+	return ParseDateAt(s, time.Now().UTC())
+}
+
+// parseDates - ParseDate of the start and end dates of the functions
+// that take them as strings, an empty endDate is now
+func parseDates(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := ParseDate(startDate)
+	if err != nil {
+		return start, start, fmt.Errorf("invalid start: %w", err)
+	}
+	end := time.Now()
+	if endDate != "" {
+		if end, err = ParseDate(endDate); err != nil {
+			return start, end, fmt.Errorf("invalid end: %w", err)
+		}
+	}
+	return start, end, nil
+}
+
+// ParseDateAt - parse a date expression relative to now. Accepted forms:
+//
+//	2021, 2021-03, 2021-03-15, 20210315   start of the year, month or day
+//	2021-03-15 09:30[:00]                 time of day, also with a T
+//	2021-03-15T09:30:00-05:00             with a zone, including RFC 3339
+//	1615800600, 1615800600000             unix epoch seconds or milliseconds
+//	now, today, yesterday, ytd, mtd       ytd and mtd are the year and month start
+//	-30d, -6h, +15m, -2w, -3mo, -1y       offset from now in seconds (s), minutes
+//	                                      (m), hours (h), days (d), weeks (w),
+//	                                      months (mo) or years (y)
+//
+// Times without a zone are in now's location.
+func ParseDateAt(s string, now time.Time) (time.Time, error) {
+	expr := strings.TrimSpace(s)
+	if expr == "" {
+		return time.Time{}, errors.New("empty date")
+	}
+
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch strings.ToLower(expr) {
+	case "now":
+		return now, nil
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "ytd":
+		return time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc), nil
+	case "mtd":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc), nil
+	}
+
+	if expr[0] == '-' || expr[0] == '+' {
+		return parseOffset(expr, now)
+	}
+
+	// epoch seconds or milliseconds, anything shorter is a year or yyyymmdd
+	if len(expr) > 8 && isDigits(expr) {
+		n, err := strconv.ParseInt(expr, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date '%s': %v", s, err)
+		}
+		if len(expr) > 11 {
+			return time.Unix(n/1000, n%1000*int64(time.Millisecond)).In(loc), nil
+		}
+		return time.Unix(n, 0).In(loc), nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, expr, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s', expected yyyy[-mm[-dd]] [hh:mm[:ss]], rfc3339, epoch seconds, today, ytd or an offset such as -30d", s)
+}
+
+// parseOffset - now moved by an expression such as -30d or +6h
+func parseOffset(expr string, now time.Time) (time.Time, error) {
+	i := 1
+	for i < len(expr) && expr[i] >= '0' && expr[i] <= '9' {
+		i++
+	}
+	n, err := strconv.Atoi(expr[1:i])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date offset '%s'", expr)
+	}
+	if expr[0] == '-' {
+		n = -n
+	}
+	switch expr[i:] {
+	case "s":
+		return now.Add(time.Duration(n) * time.Second), nil
+	case "m":
+		return now.Add(time.Duration(n) * time.Minute), nil
+	case "h":
+		return now.Add(time.Duration(n) * time.Hour), nil
+	case "d":
+		return now.AddDate(0, 0, n), nil
+	case "w":
+		return now.AddDate(0, 0, 7*n), nil
+	case "mo":
+		return now.AddDate(0, n, 0), nil
+	case "y":
+		return now.AddDate(n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date offset '%s', unit must be s, m, h, d, w, mo or y", expr)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package quote
+
+import (
+	"strconv"
+)
+
+// Decimal - a number exactly as the exchange wrote it, e.g. "0.05612000",
+// for reconciling against exchange statements without float rounding
+type Decimal string
+
+// Float64 - d as the nearest float64, 0 if it isn't a number
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(string(d), 64)
+	return f
+}
+
+func (d Decimal) String() string {
+	return string(d)
+}
+
+// floatDecimal - the shortest Decimal that parses back to f
+func floatDecimal(f float64) Decimal {
+	return Decimal(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// ExactBar - a bar's prices and volume as the exchange sent them
+type ExactBar struct {
+	Open   Decimal `json:"open"`
+	High   Decimal `json:"high"`
+	Low    Decimal `json:"low"`
+	Close  Decimal `json:"close"`
+	Volume Decimal `json:"volume"`
+}
+
+// exactBar - bar of q as an ExactBar, from Exact if q has it and
+// otherwise from the float columns
+func (q Quote) exactBar(bar int) ExactBar {
+	if q.Exact != nil {
+		return q.Exact[bar]
+	}
+	return ExactBar{
+		Open:   floatDecimal(q.Open[bar]),
+		High:   floatDecimal(q.High[bar]),
+		Low:    floatDecimal(q.Low[bar]),
+		Close:  floatDecimal(q.Close[bar]),
+		Volume: floatDecimal(q.Volume[bar]),
+	}
+}
+
+// priceFields - open, high, low, close and volume of bar formatted for
+// output, the exchange's decimals if q has them
+func (q Quote) priceFields(bar int) [5]string {
+	if q.Exact != nil {
+		e := q.Exact[bar]
+		return [5]string{string(e.Open), string(e.High), string(e.Low), string(e.Close), string(e.Volume)}
+	}
+	var f [5]string
+	precision := q.precision()
+	for i, v := range []float64{q.Open[bar], q.High[bar], q.Low[bar], q.Close[bar]} {
+		f[i] = strconv.FormatFloat(v, 'f', precision, 64)
+	}
+	f[4] = strconv.FormatFloat(q.Volume[bar], 'f', q.volumePrecision(), 64)
+	return f
+}
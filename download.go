@@ -0,0 +1,399 @@
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter - token bucket limiting how often requests are started
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens added per second, <= 0 for no limit
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter - limiter allowing perSecond requests on average with
+// bursts of up to burst requests, perSecond <= 0 disables limiting
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait - block until a request may start, returning early with
+// ctx.Err() if ctx is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		if l == nil || l.rate <= 0 {
+			return ctx.Err()
+		}
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return ctx.Err()
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// rateLimits - per source limiters, created on first use
+type rateLimits struct {
+	mu sync.Mutex
+	m  map[string]*RateLimiter
+	// delays - the delay each limiter created by get was built from,
+	// limiters set explicitly have none
+	delays map[string]time.Duration
+}
+
+// defaultRateLimits - limiters shared by the package level functions
+var defaultRateLimits = &rateLimits{}
+
+func (r *rateLimits) set(source string, l *RateLimiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.m == nil {
+		r.m = make(map[string]*RateLimiter)
+	}
+	r.m[source] = l
+	delete(r.delays, source)
+}
+
+// get - the limiter for source, created with one request per delay
+// if there isn't one yet, or rebuilt if it was created from another
+// delay
+func (r *rateLimits) get(source string, delay time.Duration) *RateLimiter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.m[source]
+	if d, auto := r.delays[source]; ok && auto && d != delay {
+		ok = false
+	}
+	if !ok {
+		var perSecond float64
+		if delay > 0 {
+			perSecond = float64(time.Second) / float64(delay)
+		}
+		l = NewRateLimiter(perSecond, 1)
+		if r.m == nil {
+			r.m = make(map[string]*RateLimiter)
+		}
+		if r.delays == nil {
+			r.delays = make(map[string]time.Duration)
+		}
+		r.m[source] = l
+		r.delays[source] = delay
+	}
+	return l
+}
+
+// SetRateLimit - limit requests to the named source to perSecond on
+// average with bursts of up to burst, shared by the package level
+// functions and Clients not made by NewClient
+func SetRateLimit(source string, perSecond float64, burst int) {
+	defaultRateLimits.set(source, NewRateLimiter(perSecond, burst))
+}
+
+// RateLimit - the package level limiter for the named source, created
+// on first use with one request per Delay milliseconds and rebuilt if
+// Delay changes, unless it was set with SetRateLimit
+func RateLimit(source string) *RateLimiter {
+	return defaultClient().RateLimit(source)
+}
+
+// DownloadResult - outcome of downloading one symbol
+type DownloadResult struct {
+	Symbol  string
+	Quote   Quote
+	Err     error
+	Elapsed time.Duration // time spent downloading, including rate limit waits between requests
+}
+
+// Bars - number of bars downloaded
+func (r DownloadResult) Bars() int {
+	return len(r.Quote.Close)
+}
+
+// DownloadResults - outcomes of downloading a list of symbols
+type DownloadResults []DownloadResult
+
+// Downloader - fetch many symbols from a Source with a pool of workers.
+// Every http request the Source makes, pages and retries included, waits
+// on the rate limit of its Client for that source.
+type Downloader struct {
+	Source      Source
+	Concurrency int          // number of workers, default 1
+	Limiter     *RateLimiter // extra limit on how often symbols start, nil for none
+	Client      *Client      // logger, nil for the default
+}
+
+// NewDownloader - downloader for src using concurrency workers
+func NewDownloader(src Source, concurrency int) *Downloader {
+	return &Downloader{Source: src, Concurrency: concurrency}
+}
+
+// Download - historical prices for each symbol, the results are in the
+// same order as symbols whatever order the downloads finish in. The
+// dates are in any form ParseDate accepts, an empty endDate is now.
+func (d *Downloader) Download(ctx context.Context, symbols []string, startDate, endDate string, period Period) DownloadResults {
+	req, err := dateRequest(symbols, startDate, endDate, period)
+	if err != nil {
+		return failAll(symbols, err)
+	}
+	return d.Fetch(ctx, req)
+}
+
+// Fetch - Download for a Request, fetching each of its symbols with
+// Source.Fetch. An invalid req fails every symbol.
+func (d *Downloader) Fetch(ctx context.Context, req Request) DownloadResults {
+	r, err := req.normalize()
+	if err == nil {
+		err = CheckPeriod(d.Source, r.Period)
+	}
+	if err != nil {
+		return failAll(req.Symbols, err)
+	}
+	return d.run(ctx, r.Symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		one := r
+		one.Symbols = []string{symbol}
+		quotes, err := d.Source.Fetch(ctx, one)
+		return onlyQuote(d.Source, symbol, quotes, err)
+	})
+}
+
+// onlyQuote - the Quote of a single symbol Fetch from src, with the
+// symbol's own error rather than a *DownloadError of one
+func onlyQuote(src Source, symbol string, quotes Quotes, err error) (Quote, error) {
+	if err != nil {
+		var derr *DownloadError
+		if errors.As(err, &derr) && len(derr.Failed) == 1 {
+			err = derr.Failed[0].Err
+		}
+		return NewQuote("", 0), err
+	}
+	if len(quotes) == 0 {
+		return NewQuote("", 0), newError(ErrSymbolNotFound, src.Name(), symbol, nil)
+	}
+	return quotes[0], nil
+}
+
+// failAll - results with err for every symbol
+func failAll(symbols []string, err error) DownloadResults {
+	results := make(DownloadResults, len(symbols))
+	for i, sym := range symbols {
+		results[i] = DownloadResult{Symbol: sym, Err: err}
+	}
+	return results
+}
+
+// run - get each symbol with a pool of workers
+func (d *Downloader) run(ctx context.Context, symbols []string, get func(ctx context.Context, symbol string) (Quote, error)) DownloadResults {
+
+	results := make(DownloadResults, len(symbols))
+	for i, sym := range symbols {
+		results[i].Symbol = sym
+	}
+
+	workers := d.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(symbols) {
+		workers = len(symbols)
+	}
+	c := d.Client
+	if c == nil {
+		c = defaultClient()
+	}
+	limiter := d.Limiter
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := &results[i]
+				if r.Err = limiter.Wait(ctx); r.Err != nil {
+					continue
+				}
+				start := time.Now()
+				r.Quote, r.Err = get(ctx, r.Symbol)
+				r.Elapsed = time.Since(start)
+				if r.Err != nil {
+					c.logError("download failed", d.Source.Name(), r.Symbol, r.Err, F("elapsed", r.Elapsed))
+				} else {
+					c.log(LevelInfo, "downloaded", F("source", d.Source.Name()), F("symbol", r.Symbol), F("bars", r.Bars()), F("elapsed", r.Elapsed))
+				}
+			}
+		}()
+	}
+
+	for i := range symbols {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// Quotes - the quotes that downloaded successfully, in symbol order
+func (results DownloadResults) Quotes() Quotes {
+	quotes := Quotes{}
+	for _, r := range results {
+		if r.Err == nil {
+			quotes = append(quotes, r.Quote)
+		}
+	}
+	return quotes
+}
+
+// Failed - the results that have an error, in symbol order
+func (results DownloadResults) Failed() DownloadResults {
+	var failed DownloadResults
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// Err - nil if every symbol downloaded, otherwise a *DownloadError
+// holding all the failures
+func (results DownloadResults) Err() error {
+	failed := results.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return &DownloadError{Failed: failed, Total: len(results)}
+}
+
+// Summary - human readable report of the downloads, one line per failure
+func (results DownloadResults) Summary() string {
+	var bars int
+	var elapsed time.Duration
+	for _, r := range results {
+		bars += r.Bars()
+		elapsed += r.Elapsed
+	}
+	failed := results.Failed()
+
+	var buffer strings.Builder
+	buffer.WriteString(fmt.Sprintf("downloaded %d of %d symbols, %d bars in %v\n",
+		len(results)-len(failed), len(results), bars, elapsed.Round(time.Millisecond)))
+	for _, r := range failed {
+		buffer.WriteString(fmt.Sprintf("  %s: %v\n", r.Symbol, r.Err))
+	}
+	return buffer.String()
+}
+
+// DownloadError - the symbols that failed to download in a batch
+type DownloadError struct {
+	Failed DownloadResults // failed symbols with their errors
+	Total  int             // number of symbols requested
+}
+
+func (e *DownloadError) Error() string {
+	msgs := make([]string, len(e.Failed))
+	for i, r := range e.Failed {
+		msgs[i] = r.Err.Error()
+		if !strings.Contains(msgs[i], r.Symbol) {
+			msgs[i] = r.Symbol + ": " + msgs[i]
+		}
+	}
+	return fmt.Sprintf("%d of %d symbols failed: %s", len(e.Failed), e.Total, strings.Join(msgs, "; "))
+}
+
+// Is - report whether the error of any failed symbol matches target,
+// for errors.Is on toolchains before Go 1.20 that don't follow Unwrap
+// to a list of errors
+func (e *DownloadError) Is(target error) bool {
+	for _, r := range e.Failed {
+		if errors.Is(r.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As - set target to the first error of a failed symbol that matches
+// it, for errors.As on toolchains before Go 1.20
+func (e *DownloadError) As(target interface{}) bool {
+	for _, r := range e.Failed {
+		if errors.As(r.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap - the error of each failed symbol, for errors.Is and errors.As
+func (e *DownloadError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, r := range e.Failed {
+		errs[i] = r.Err
+	}
+	return errs
+}
+
+// downloadEach - fetch symbols one at a time, pausing c.Delay between
+// them, stopping early with ctx.Err() if ctx is done
+func (c *Client) downloadEach(ctx context.Context, source string, symbols []string, fetch func(ctx context.Context, symbol string) (Quote, error)) (DownloadResults, error) {
+	results := make(DownloadResults, 0, len(symbols))
+	for _, symbol := range symbols {
+		start := time.Now()
+		quote, err := fetch(ctx, symbol)
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		elapsed := time.Since(start)
+		if err != nil {
+			c.logError("download failed", source, symbol, err, F("elapsed", elapsed))
+		} else {
+			c.log(LevelInfo, "downloaded", F("source", source), F("symbol", symbol), F("bars", len(quote.Close)), F("elapsed", elapsed))
+		}
+		results = append(results, DownloadResult{Symbol: symbol, Quote: quote, Err: err, Elapsed: elapsed})
+		if len(results) == len(symbols) {
+			break
+		}
+		if err := sleepContext(ctx, c.Delay); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// symbolsFromFile - one symbol per line, as written
+func symbolsFromFile(filename string) ([]string, error) {
+	raw, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return deleteEmpty(strings.Split(strings.ReplaceAll(string(raw), "\r", ""), "\n")), nil
+}
@@ -0,0 +1,107 @@
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Error kinds, test for them with errors.Is
+var (
+	// ErrSymbolNotFound - the source doesn't know the symbol
+	ErrSymbolNotFound = errors.New("symbol not found")
+	// ErrRateLimited - the source refused the request, retry later
+	ErrRateLimited = errors.New("rate limited")
+	// ErrAuthFailed - missing, invalid or unauthorized api token
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrMalformedResponse - the response couldn't be parsed
+	ErrMalformedResponse = errors.New("malformed response")
+	// ErrInvalidPeriod - the source doesn't support the period
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrBadStatus - any other unexpected http status, or ftp reply code
+	ErrBadStatus = errors.New("unexpected http status")
+	// ErrNetwork - the connection failed or broke off
+	ErrNetwork = errors.New("network failure")
+)
+
+// Error - a failed download, with the source, symbol and http status
+// involved. errors.Is matches its Kind, errors.As finds the Error itself
+// and errors.Unwrap returns the underlying cause, if any.
+type Error struct {
+	Kind   error  // one of the Err* kinds above
+	Source string // source name, e.g. "binance"
+	Symbol string // symbol or market requested, may be empty
+	Status int    // http status code, 0 if there was no response
+	Err    error  // underlying cause, may be nil
+
+	// RetryAfter - wait the source asked for before the next request,
+	// 0 if it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := e.Source
+	if e.Symbol != "" {
+		msg += " " + e.Symbol
+	}
+	msg += ": " + e.Kind.Error()
+	if e.Status != 0 {
+		msg += fmt.Sprintf(" (http %d)", e.Status)
+	}
+	if e.RetryAfter != 0 {
+		msg += fmt.Sprintf(", retry after %v", e.RetryAfter)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is - report whether target is the kind of e
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap - the underlying cause of e
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError - an *Error without an http status
+func newError(kind error, source, symbol string, cause error) *Error {
+	return &Error{Kind: kind, Source: source, Symbol: symbol, Err: cause}
+}
+
+// malformed - an *Error for a response that couldn't be parsed
+func malformed(source, symbol string, cause error) *Error {
+	return newError(ErrMalformedResponse, source, symbol, cause)
+}
+
+// netErr - an ErrNetwork *Error for a connection that failed or broke
+// off, or the context error once ctx is done
+func netErr(ctx context.Context, source, symbol string, cause error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return newError(ErrNetwork, source, symbol, cause)
+}
+
+// checkStatus - an *Error for any response that isn't 200 OK
+func checkStatus(resp *http.Response, source, symbol string) error {
+	var kind error
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		kind = ErrSymbolNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrAuthFailed
+	case http.StatusTooManyRequests, 418:
+		kind = ErrRateLimited
+	default:
+		kind = ErrBadStatus
+	}
+	return &Error{Kind: kind, Source: source, Symbol: symbol, Status: resp.StatusCode}
+}
@@ -0,0 +1,60 @@
+package quote
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPConfig - http client and base url settings shared by all fetchers
+type HTTPConfig struct {
+	// Client - used for every request, nil for a default client
+	Client *http.Client
+	// Transport - round tripper for the default client, ignored when
+	// Client is set
+	Transport http.RoundTripper
+	// Timeout - request timeout for the default client, 0 for ClientTimeout
+	Timeout time.Duration
+	// BaseURLs - base url overrides, keyed as in DefaultBaseURLs
+	BaseURLs map[string]string
+	// Retry - how transient failures are retried
+	Retry RetryPolicy
+}
+
+// HTTP - http settings used by the package level fetchers and market
+// lists, replace to run against a mock server, a proxy or a regional mirror.
+//
+// Deprecated: set Client.HTTP instead.
+var HTTP = HTTPConfig{Retry: DefaultRetryPolicy}
+
+// DefaultBaseURLs - base urls used unless overridden in HTTPConfig.BaseURLs,
+// "nasdaq-ftp" is a host:port address rather than a url
+var DefaultBaseURLs = map[string]string{
+	"yahoo":      "https://query1.finance.yahoo.com",
+	"yahoo-init": "https://finance.yahoo.com",
+	"tiingo":     "https://api.tiingo.com",
+	"coinbase":   "https://api.pro.coinbase.com",
+	"bittrex":    "https://bittrex.com",
+	"binance":    "https://api.binance.com",
+	"nasdaq-ftp": "ftp.nasdaqtrader.com:21",
+}
+
+// client - the http client to use for requests
+func (c HTTPConfig) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = ClientTimeout
+	}
+	return &http.Client{Timeout: timeout, Transport: c.Transport}
+}
+
+// baseURL - base url for name, without a trailing slash
+func (c HTTPConfig) baseURL(name string) string {
+	if u, ok := c.BaseURLs[name]; ok && u != "" {
+		return strings.TrimSuffix(u, "/")
+	}
+	return DefaultBaseURLs[name]
+}
@@ -0,0 +1,183 @@
+package quote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level - severity of a log entry
+type Level int
+
+// Log levels, in increasing severity
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel - level from its name: debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	if strings.EqualFold(s, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, fmt.Errorf("invalid log level '%s', must be debug, info, warn or error", s)
+}
+
+// Field - a key value pair attached to a log entry, such as source,
+// symbol, url, status or elapsed
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F - shorthand for Field{key, value}
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Logger - receives the diagnostics of a Client. Implementations must
+// be safe for concurrent use.
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// LoggerFunc - adapter to use an ordinary function as a Logger
+type LoggerFunc func(level Level, msg string, fields ...Field)
+
+// Log - call f
+func (f LoggerFunc) Log(level Level, msg string, fields ...Field) {
+	f(level, msg, fields...)
+}
+
+// DiscardLogger - Logger that drops everything
+var DiscardLogger Logger = LoggerFunc(func(Level, string, ...Field) {})
+
+type stdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+// NewStdLogger - Logger writing entries of at least level min to l, as
+// "level msg key=value ..." lines
+func NewStdLogger(l *log.Logger, min Level) Logger {
+	return stdLogger{l: l, min: min}
+}
+
+func (s stdLogger) Log(level Level, msg string, fields ...Field) {
+	if level < s.min {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for _, f := range fields {
+		v := fmt.Sprint(f.Value)
+		if strings.ContainsAny(v, " \t\n\"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+		fmt.Fprintf(&b, " %s=%s", f.Key, v)
+	}
+	// skip Log and Client.log to report the caller
+	s.l.Output(3, b.String())
+}
+
+type jsonLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	min Level
+}
+
+// NewJSONLogger - Logger writing entries of at least level min to w, one
+// json object per line with time, level, msg and the fields as keys.
+// Errors and values with a String method are written as strings.
+func NewJSONLogger(w io.Writer, min Level) Logger {
+	return &jsonLogger{w: w, min: min}
+}
+
+func (j *jsonLogger) Log(level Level, msg string, fields ...Field) {
+	if level < j.min {
+		return
+	}
+	// build the object by hand to keep the keys in order
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSON(&b, time.Now().UTC().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, level.String())
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, msg)
+	for _, f := range fields {
+		b.WriteByte(',')
+		writeJSON(&b, f.Key)
+		b.WriteByte(':')
+		switch v := f.Value.(type) {
+		case error:
+			writeJSON(&b, v.Error())
+		case fmt.Stringer:
+			writeJSON(&b, v.String())
+		default:
+			writeJSON(&b, v)
+		}
+	}
+	b.WriteString("}\n")
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	io.WriteString(j.w, b.String())
+}
+
+func writeJSON(b *strings.Builder, v interface{}) {
+	buf, err := json.Marshal(v)
+	if err != nil {
+		buf, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(buf)
+}
+
+// log - send an entry to c.Log, if set
+func (c *Client) log(level Level, msg string, fields ...Field) {
+	if c.Log != nil {
+		c.Log.Log(level, msg, fields...)
+	}
+}
+
+// logError - log err at error level with the source and symbol it
+// concerns, the http status if err is an *Error that has one, and any
+// other fields
+func (c *Client) logError(msg, source, symbol string, err error, extra ...Field) {
+	if c.Log == nil {
+		return
+	}
+	fields := []Field{F("source", source)}
+	if symbol != "" {
+		fields = append(fields, F("symbol", symbol))
+	}
+	var e *Error
+	if errors.As(err, &e) && e.Status != 0 {
+		fields = append(fields, F("status", e.Status))
+	}
+	fields = append(fields, extra...)
+	c.Log.Log(LevelError, msg, append(fields, F("error", err))...)
+}
@@ -0,0 +1,147 @@
+package quote
+
+import (
+	"fmt"
+	"sort"
+)
+
+// MergePolicy - which bar Merge keeps when both quotes have one dated
+// the same time
+type MergePolicy int
+
+const (
+	// PreferNewer - keep the bar from the quote being merged in, e.g. a
+	// fresh download replacing the last bars of a stored history
+	PreferNewer MergePolicy = iota
+	// PreferOlder - keep the bar already in the quote
+	PreferOlder
+	// ErrorOnConflict - fail if the bars differ, identical bars are
+	// merged
+	ErrorOnConflict
+)
+
+func (p MergePolicy) String() string {
+	switch p {
+	case PreferNewer:
+		return "newer"
+	case PreferOlder:
+		return "older"
+	case ErrorOnConflict:
+		return "error"
+	}
+	return fmt.Sprintf("MergePolicy(%d)", int(p))
+}
+
+// Merge - the bars of q and other in date order with one bar per date,
+// chosen by policy. Duplicate dates within q or other are resolved the
+// same way, later bars counting as newer, so q.Merge(Quote{}, PreferNewer)
+// sorts q and drops its duplicates. Optional columns only one of them
+// has are dropped rather than made up for the other's bars, unless the
+// other has no bars. q and other are not modified.
+func (q Quote) Merge(other Quote, policy MergePolicy) (Quote, error) {
+	if q.Symbol != "" && other.Symbol != "" && q.Symbol != other.Symbol {
+		return Quote{}, fmt.Errorf("merge %s with %s", q.Symbol, other.Symbol)
+	}
+	if q.Len() > 0 && other.Len() > 0 && q.BarTime != other.BarTime {
+		return Quote{}, fmt.Errorf("merge %s bars stamped with their %s time with bars stamped with their %s time",
+			q.Symbol, q.BarTime, other.BarTime)
+	}
+
+	all := Quote{Symbol: q.Symbol, Precision: q.Precision, VolumePrecision: q.VolumePrecision, BarTime: q.BarTime}
+	if all.Symbol == "" {
+		all.Symbol = other.Symbol
+	}
+	if other.Precision > all.Precision {
+		all.Precision = other.Precision
+	}
+	if other.VolumePrecision > all.VolumePrecision {
+		all.VolumePrecision = other.VolumePrecision
+	}
+	if q.Len() == 0 {
+		all.BarTime = other.BarTime
+	}
+	all.append(q)
+	all.append(other)
+
+	order := make([]int, all.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return all.Date[order[i]].Before(all.Date[order[j]]) })
+
+	keep := make([]int, 0, len(order))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && all.Date[order[end]].Equal(all.Date[order[start]]) {
+			end++
+		}
+		switch policy {
+		case PreferNewer:
+			keep = append(keep, order[end-1])
+		case PreferOlder:
+			keep = append(keep, order[start])
+		case ErrorOnConflict:
+			for _, i := range order[start+1 : end] {
+				if !all.sameBar(order[start], i) {
+					return Quote{}, fmt.Errorf("merge %s: conflicting bars at %s", all.Symbol, all.Date[i].Format(csvDateFormat))
+				}
+			}
+			keep = append(keep, order[start])
+		default:
+			return Quote{}, fmt.Errorf("merge %s: unknown policy %s", all.Symbol, policy)
+		}
+		start = end
+	}
+	return all.pick(keep), nil
+}
+
+// sameBar - report whether bars i and j of q hold the same values
+func (q Quote) sameBar(i, j int) bool {
+	a, b := q.Bar(i), q.Bar(j)
+	b.Date = a.Date
+	if a != b {
+		return false
+	}
+	if q.Exact != nil && q.Exact[i] != q.Exact[j] {
+		return false
+	}
+	for _, col := range q.extras() {
+		if *col != nil && (*col)[i] != (*col)[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// pick - a new Quote with bars idx of q, in that order
+func (q Quote) pick(idx []int) Quote {
+	p := NewQuote(q.Symbol, len(idx))
+	p.Precision = q.Precision
+	p.VolumePrecision = q.VolumePrecision
+	p.BarTime = q.BarTime
+	for n, i := range idx {
+		p.Date[n] = q.Date[i]
+		p.Open[n] = q.Open[i]
+		p.High[n] = q.High[i]
+		p.Low[n] = q.Low[i]
+		p.Close[n] = q.Close[i]
+		p.Volume[n] = q.Volume[i]
+	}
+	if q.Exact != nil {
+		p.Exact = make([]ExactBar, len(idx))
+		for n, i := range idx {
+			p.Exact[n] = q.Exact[i]
+		}
+	}
+	cols := p.extras()
+	for c, col := range q.extras() {
+		if *col == nil {
+			continue
+		}
+		*cols[c] = make([]float64, len(idx))
+		for n, i := range idx {
+			(*cols[c])[n] = (*col)[i]
+		}
+	}
+	return p
+}
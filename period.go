@@ -0,0 +1,172 @@
+package quote
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Period - for quote history. Min1 to Min60 were the seconds 60, 300,
+// 900, 1800 and 3600 before; ParsePeriod still accepts those spellings.
+type Period string
+
+const (
+	// Min1 - 1 Minute time period
+	Min1 Period = "1m"
+	// Min3 - 3 Minute time period
+	Min3 Period = "3m"
+	// Min5 - 5 Minute time period
+	Min5 Period = "5m"
+	// Min15 - 15 Minute time period
+	Min15 Period = "15m"
+	// Min30 - 30 Minute time period
+	Min30 Period = "30m"
+	// Min60 - 60 Minute time period
+	Min60 Period = "1h"
+	// Hour2 - 2 hour time period
+	Hour2 Period = "2h"
+	// Hour4 - 4 hour time period
+	Hour4 Period = "4h"
+	// Hour6 - 6 hour time period
+	Hour6 Period = "6h"
+	// Hour8 - 8 hour time period
+	Hour8 Period = "8h"
+	// Hour12 - 12 hour time period
+	Hour12 Period = "12h"
+	// Daily time period
+	Daily Period = "d"
+	// Day3 - 3 day time period
+	Day3 Period = "3d"
+	// Weekly time period
+	Weekly Period = "w"
+	// Monthly time period
+	Monthly Period = "m"
+)
+
+// periods - every Period, shortest first, with its nominal length and
+// the other spellings ParsePeriod accepts for it
+var periods = []struct {
+	period   Period
+	duration time.Duration
+	aliases  []string
+}{
+	{Min1, time.Minute, []string{"60"}},
+	{Min3, 3 * time.Minute, nil},
+	{Min5, 5 * time.Minute, []string{"300"}},
+	{Min15, 15 * time.Minute, []string{"900"}},
+	{Min30, 30 * time.Minute, []string{"1800"}},
+	{Min60, time.Hour, []string{"60m", "3600"}},
+	{Hour2, 2 * time.Hour, nil},
+	{Hour4, 4 * time.Hour, nil},
+	{Hour6, 6 * time.Hour, nil},
+	{Hour8, 8 * time.Hour, nil},
+	{Hour12, 12 * time.Hour, nil},
+	{Daily, 24 * time.Hour, []string{"1d"}},
+	{Day3, 3 * 24 * time.Hour, nil},
+	{Weekly, 7 * 24 * time.Hour, []string{"1w"}},
+	{Monthly, 30 * 24 * time.Hour, []string{"1M"}},
+}
+
+// Periods - every supported Period, shortest first
+func Periods() []Period {
+	ps := make([]Period, len(periods))
+	for i, p := range periods {
+		ps[i] = p.period
+	}
+	return ps
+}
+
+// ParsePeriod - Period from a string such as 1m, 15m, 1h, d, 1d, w or
+// 1M. The seconds encodings used by earlier versions (60, 300, ...) are
+// also accepted. The result's String is the canonical spelling.
+func ParsePeriod(s string) (Period, error) {
+	for _, p := range periods {
+		if s == string(p.period) {
+			return p.period, nil
+		}
+		for _, a := range p.aliases {
+			if s == a {
+				return p.period, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid period '%s', must be one of %s", s, joinPeriods(Periods()))
+}
+
+// canonical - the canonical spelling of p, so Period("3600") works as
+// Min60, p itself if ParsePeriod doesn't accept it
+func canonical(p Period) Period {
+	if c, err := ParsePeriod(string(p)); err == nil {
+		return c
+	}
+	return p
+}
+
+// String - canonical spelling, as accepted by ParsePeriod
+func (p Period) String() string {
+	return string(p)
+}
+
+// Duration - nominal length of a bar, 0 for an unknown Period. Months
+// are taken as 30 days.
+func (p Period) Duration() time.Duration {
+	p = canonical(p)
+	for _, e := range periods {
+		if e.period == p {
+			return e.duration
+		}
+	}
+	return 0
+}
+
+// sourcePeriods - the periods each built in source supports
+var sourcePeriods = map[string][]Period{
+	"yahoo":         {Daily},
+	"tiingo":        {Daily},
+	"tiingo-crypto": {Min1, Min3, Min5, Min15, Min30, Min60, Hour2, Hour4, Hour6, Hour8, Hour12, Daily},
+	"coinbase":      {Min1, Min5, Min15, Min60, Hour6, Daily},
+	"bittrex":       {Min1, Min5, Min30, Min60, Daily},
+	"binance":       {Min1, Min3, Min5, Min15, Min30, Min60, Hour2, Hour4, Hour6, Hour8, Hour12, Daily, Day3, Weekly, Monthly},
+}
+
+// SupportsPeriod - report whether src can download bars of period
+func SupportsPeriod(src Source, period Period) bool {
+	period = canonical(period)
+	for _, p := range src.Periods() {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPeriod - an ErrInvalidPeriod *Error if src can't download bars
+// of period, nil otherwise
+func CheckPeriod(src Source, period Period) error {
+	if SupportsPeriod(src, period) {
+		return nil
+	}
+	return newError(ErrInvalidPeriod, src.Name(), "",
+		fmt.Errorf("'%s' not supported, must be one of %s", period, joinPeriods(src.Periods())))
+}
+
+// checkPeriod - CheckPeriod for the built in source named source,
+// returning period in its canonical spelling
+func checkPeriod(source, symbol string, period Period) (Period, error) {
+	c := canonical(period)
+	for _, p := range sourcePeriods[source] {
+		if p == c {
+			return c, nil
+		}
+	}
+	return period, newError(ErrInvalidPeriod, source, symbol,
+		fmt.Errorf("'%s' not supported, must be one of %s", period, joinPeriods(sourcePeriods[source])))
+}
+
+func joinPeriods(ps []Period) string {
+	s := make([]string, len(ps))
+	for i, p := range ps {
+		s[i] = string(p)
+	}
+	return strings.Join(s, ", ")
+}
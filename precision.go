@@ -0,0 +1,213 @@
+package quote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// DefaultPrecision - decimal places written for a Quote without a Precision
+const DefaultPrecision = 2
+
+// NoDecimals - Precision of whole numbers, as a Precision of 0 means
+// DefaultPrecision
+const NoDecimals = -1
+
+// sourcePrecisions - decimal places of the built in sources that don't
+// publish tick sizes, and the fallback for the ones that do
+var sourcePrecisions = map[string]int64{
+	"yahoo":         2,
+	"tiingo":        2,
+	"tiingo-crypto": 8,
+	"coinbase":      8,
+	"bittrex":       8,
+	"binance":       8,
+}
+
+// precisionCache - decimal places per source and symbol from exchange
+// metadata, so it is downloaded once
+type precisionCache struct {
+	mu sync.Mutex
+	m  map[string]int64
+}
+
+// defaultPrecisions - precisions shared by the package level functions
+var defaultPrecisions = &precisionCache{}
+
+func (p *precisionCache) get(key string) (int64, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n, ok := p.m[key]
+	return n, ok
+}
+
+func (p *precisionCache) set(key string, n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.m == nil {
+		p.m = make(map[string]int64)
+	}
+	p.m[key] = n
+}
+
+func (c *Client) precisionCache() *precisionCache {
+	if c.precisions == nil {
+		return defaultPrecisions
+	}
+	return c.precisions
+}
+
+// precision - Quote.Precision and VolumePrecision for symbol from source.
+// Prices get c.Precision if set, otherwise the exchange's tick size where
+// it publishes one, otherwise the source default. Volumes get the source
+// default. Metadata failures are logged, not returned.
+func (c *Client) precision(ctx context.Context, source, symbol string) (int64, int64) {
+	def := sourcePrecisions[source]
+	if c.Precision != 0 {
+		return c.Precision, def
+	}
+	var url string
+	switch source {
+	case "binance":
+		url = fmt.Sprintf("%s/api/v3/exchangeInfo?symbol=%s", c.HTTP.baseURL("binance"), strings.ToUpper(symbol))
+	case "coinbase":
+		url = fmt.Sprintf("%s/products/%s", c.HTTP.baseURL("coinbase"), symbol)
+	default:
+		return def, def
+	}
+
+	key := source + ":" + strings.ToUpper(symbol)
+	n, ok := c.precisionCache().get(key)
+	if !ok {
+		var err error
+		if n, err = c.tickPrecision(ctx, source, symbol, url); err != nil {
+			if ctx.Err() == nil {
+				c.logError("no tick size, using default precision", source, symbol, err, F("precision", def))
+			}
+			return def, def
+		}
+		c.precisionCache().set(key, n)
+	}
+	if n == 0 {
+		// a whole number tick, e.g. "1"
+		return NoDecimals, def
+	}
+	return n, def
+}
+
+// tickPrecision - decimal places of the price tick size in the binance
+// exchangeInfo or coinbase product at url
+func (c *Client) tickPrecision(ctx context.Context, source, symbol, url string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.do(req, source, symbol)
+	if err != nil {
+		return 0, ctxErr(ctx, err)
+	}
+	defer resp.Body.Close()
+	if err = checkStatus(resp, source, symbol); err != nil {
+		return 0, err
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, netErr(ctx, source, symbol, err)
+	}
+
+	var tick string
+	if source == "binance" {
+		var info struct {
+			Symbols []struct {
+				Symbol  string `json:"symbol"`
+				Filters []struct {
+					FilterType string `json:"filterType"`
+					TickSize   string `json:"tickSize"`
+				} `json:"filters"`
+			} `json:"symbols"`
+		}
+		if err := json.Unmarshal(contents, &info); err != nil {
+			return 0, malformed(source, symbol, err)
+		}
+		for _, s := range info.Symbols {
+			if !strings.EqualFold(s.Symbol, symbol) {
+				continue
+			}
+			for _, f := range s.Filters {
+				if f.FilterType == "PRICE_FILTER" {
+					tick = f.TickSize
+				}
+			}
+		}
+	} else {
+		var product struct {
+			QuoteIncrement string `json:"quote_increment"`
+		}
+		if err := json.Unmarshal(contents, &product); err != nil {
+			return 0, malformed(source, symbol, err)
+		}
+		tick = product.QuoteIncrement
+	}
+	if tick == "" {
+		return 0, malformed(source, symbol, fmt.Errorf("no price tick size"))
+	}
+	return decimals(tick), nil
+}
+
+// decimals - decimal places of a tick size such as "0.01000000"
+func decimals(tick string) int64 {
+	i := strings.IndexByte(tick, '.')
+	if i < 0 {
+		return 0
+	}
+	return int64(len(strings.TrimRight(tick[i+1:], "0")))
+}
+
+// csvPrecision - the most decimal places in fields, the Precision of a
+// quote read from csv
+func csvPrecision(fields []string) int64 {
+	var n int64
+	for _, f := range fields {
+		if i := strings.IndexByte(f, '.'); i >= 0 && int64(len(f)-i-1) > n {
+			n = int64(len(f) - i - 1)
+		}
+	}
+	return n
+}
+
+// precision - decimal places to write q's prices with
+func (q Quote) precision() int {
+	switch {
+	case q.Precision > 0:
+		return int(q.Precision)
+	case q.Precision == NoDecimals:
+		return 0
+	}
+	return DefaultPrecision
+}
+
+// volumePrecision - decimal places to write q's volumes with
+func (q Quote) volumePrecision() int {
+	switch {
+	case q.VolumePrecision > 0:
+		return int(q.VolumePrecision)
+	case q.VolumePrecision == NoDecimals:
+		return 0
+	}
+	return q.precision()
+}
+
+// extraPrecision - decimal places to write q's optional column i with
+func (q Quote) extraPrecision(i int) int {
+	switch {
+	case extraColumns[i].count:
+		return 0
+	case extraColumns[i].volume:
+		return q.volumePrecision()
+	}
+	return q.precision()
+}
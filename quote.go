@@ -10,8 +10,8 @@ Licensed under terms of MIT license (see LICENSE)
 package quote
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
@@ -22,7 +22,6 @@ import (
 	"net/http"
 	"net/textproto"
 	"net/url"
-	"os"
 	"regexp"
 	"sort"
 	"strconv"
@@ -33,62 +32,54 @@ import (
 // Quote - stucture for historical price data
 type Quote struct {
 	Symbol    string      `json:"symbol"`
-	Precision int64       `json:"-"`
+	Precision int64       `json:"precision,omitempty"` // decimal places, 0 for DefaultPrecision, NoDecimals for none
+	BarTime   BarTime     `json:"bartime"`             // convention of the dates
 	Date      []time.Time `json:"date"`
 	Open      []float64   `json:"open"`
 	High      []float64   `json:"high"`
 	Low       []float64   `json:"low"`
 	Close     []float64   `json:"close"`
 	Volume    []float64   `json:"volume"`
+
+	// VolumePrecision - decimal places of the volume columns, 0 for
+	// Precision, NoDecimals for none
+	VolumePrecision int64 `json:"volumeprecision,omitempty"`
+
+	// adjusted prices and volume alongside the raw ones above, nil
+	// unless the source supplies them
+	AdjOpen   []float64 `json:"adjopen,omitempty"`
+	AdjHigh   []float64 `json:"adjhigh,omitempty"`
+	AdjLow    []float64 `json:"adjlow,omitempty"`
+	AdjClose  []float64 `json:"adjclose,omitempty"`
+	AdjVolume []float64 `json:"adjvolume,omitempty"`
+
+	// exchange statistics of each bar, nil unless the source supplies them
+	QuoteVolume         []float64 `json:"quotevolume,omitempty"`         // volume in the quote currency
+	Trades              []float64 `json:"trades,omitempty"`              // number of trades
+	TakerBuyVolume      []float64 `json:"takerbuyvolume,omitempty"`      // volume bought by takers
+	TakerBuyQuoteVolume []float64 `json:"takerbuyquotevolume,omitempty"` // TakerBuyVolume in the quote currency
+
+	// Exact - the exchange's own decimals for each bar, nil unless
+	// Client.Exact is set. The writers use them in place of the float
+	// columns, so clear Exact after changing prices.
+	Exact []ExactBar `json:"exact,omitempty"`
 }
 
 // Quotes - an array of historical price data
 type Quotes []Quote
 
-// Period - for quote history
-type Period string
-
 // ClientTimeout - connect/read timeout for client requests
 const ClientTimeout = 10 * time.Second
 
-const (
-	// Min1 - 1 Minute time period
-	Min1 Period = "60"
-	// Min3 - 3 Minute time period
-	Min3 Period = "3m"
-	// Min5 - 5 Minute time period
-	Min5 Period = "300"
-	// Min15 - 15 Minute time period
-	Min15 Period = "900"
-	// Min30 - 30 Minute time period
-	Min30 Period = "1800"
-	// Min60 - 60 Minute time period
-	Min60 Period = "3600"
-	// Hour2 - 2 hour time period
-	Hour2 Period = "2h"
-	// Hour4 - 4 hour time period
-	Hour4 Period = "4h"
-	// Hour6 - 6 hour time period
-	Hour6 Period = "6h"
-	// Hour8 - 8 hour time period
-	Hour8 Period = "8h"
-	// Hour12 - 12 hour time period
-	Hour12 Period = "12h"
-	// Daily time period
-	Daily Period = "d"
-	// Day3 - 3 day time period
-	Day3 Period = "3d"
-	// Weekly time period
-	Weekly Period = "w"
-	// Monthly time period
-	Monthly Period = "m"
-)
-
-// Log - standard logger, disabled by default
+// Log - standard logger for the package level functions, disabled by default
+//
+// Deprecated: set Client.Log instead.
 var Log *log.Logger
 
 // Delay - time delay in milliseconds between quote requests (default=100)
 // Be nice, don't get blocked
+//
+// Deprecated: set Client.Delay instead.
 var Delay time.Duration
 
 func init() {
@@ -109,36 +100,262 @@ func NewQuote(symbol string, bars int) Quote {
 	}
 }
 
-// ParseDateString - parse a potentially partial date string to Time
+// truncate - keep only the first n bars
+func (q *Quote) truncate(n int) {
+	*q = q.Slice(0, n)
+}
+
+// extraColumns - csv names of the optional columns, in Quote field
+// order, and whether they hold counts or volumes rather than prices
+var extraColumns = []struct {
+	name   string
+	count  bool
+	volume bool
+}{
+	{"adjopen", false, false},
+	{"adjhigh", false, false},
+	{"adjlow", false, false},
+	{"adjclose", false, false},
+	{"adjvolume", false, true},
+	{"quotevolume", false, true},
+	{"trades", true, false},
+	{"takerbuyvolume", false, true},
+	{"takerbuyquotevolume", false, true},
+}
+
+// extras - the optional columns of q, in extraColumns order
+func (q *Quote) extras() []*[]float64 {
+	return []*[]float64{&q.AdjOpen, &q.AdjHigh, &q.AdjLow, &q.AdjClose, &q.AdjVolume,
+		&q.QuoteVolume, &q.Trades, &q.TakerBuyVolume, &q.TakerBuyQuoteVolume}
+}
+
+// extraIndexes - extraColumns indexes of the optional columns q has
+func (q Quote) extraIndexes() []int {
+	var idx []int
+	for i, col := range q.extras() {
+		if *col != nil {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+// append - add the bars of o to the end of q. Optional columns only
+// one of them has are dropped, there being no values for the other's
+// bars, unless the other has no bars at all.
+func (q *Quote) append(o Quote) {
+	n := len(q.Date)
+	if q.Exact != nil || (o.Exact != nil && n == 0) {
+		for bar := range o.Date {
+			q.Exact = append(q.Exact, o.exactBar(bar))
+		}
+	} else if o.Exact != nil {
+		exact := make([]ExactBar, n, n+len(o.Exact))
+		for bar := range exact {
+			exact[bar] = q.exactBar(bar)
+		}
+		q.Exact = append(exact, o.Exact...)
+	}
+	q.Date = append(q.Date, o.Date...)
+	q.Open = append(q.Open, o.Open...)
+	q.High = append(q.High, o.High...)
+	q.Low = append(q.Low, o.Low...)
+	q.Close = append(q.Close, o.Close...)
+	q.Volume = append(q.Volume, o.Volume...)
+	cols := o.extras()
+	for i, col := range q.extras() {
+		switch {
+		case len(o.Date) == 0:
+		case n == 0 && *col == nil:
+			*col = append([]float64(nil), *cols[i]...)
+		case *col == nil || *cols[i] == nil:
+			*col = nil
+		default:
+			*col = append(*col, *cols[i]...)
+		}
+	}
+}
+
+// csvExtraColumns - extraColumns indexes of the columns of header after
+// the first skip, which must all be optional columns
+func csvExtraColumns(header string, skip int) ([]int, error) {
+	cols := strings.Split(strings.TrimRight(header, "\r"), ",")
+	var idx []int
+	for c := skip; c < len(cols); c++ {
+		found := false
+		for i, col := range extraColumns {
+			if cols[c] == col.name {
+				idx = append(idx, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("csv header: unknown column '%s'", cols[c])
+		}
+	}
+	return idx, nil
+}
+
+// csvExtraHeader - the header names of the optional columns idx
+func csvExtraHeader(idx []int) string {
+	var buffer bytes.Buffer
+	for _, i := range idx {
+		buffer.WriteString("," + extraColumns[i].name)
+	}
+	return buffer.String()
+}
+
+// csvExtraValues - the optional columns idx of bar as csv fields
+func (q Quote) csvExtraValues(idx []int, bar int) string {
+	var buffer bytes.Buffer
+	cols := q.extras()
+	for _, i := range idx {
+		buffer.WriteString(fmt.Sprintf(",%.*f", q.extraPrecision(i), (*cols[i])[bar]))
+	}
+	return buffer.String()
+}
+
+// notePrecision - raise q's Precision and VolumePrecision to the decimal
+// places of a csv row's open..volume fields and optional columns extra
+func (q *Quote) notePrecision(fields []string, extra []int) {
+	prices := append([]string(nil), fields[:4]...)
+	volumes := []string{fields[4]}
+	for c, i := range extra {
+		switch {
+		case extraColumns[i].volume:
+			volumes = append(volumes, fields[5+c])
+		case !extraColumns[i].count:
+			prices = append(prices, fields[5+c])
+		}
+	}
+	if p := csvPrecision(prices); p > q.Precision {
+		q.Precision = p
+	}
+	if p := csvPrecision(volumes); p > q.VolumePrecision {
+		q.VolumePrecision = p
+	}
+}
+
+// parseFloats - parse each string as a float64
+func parseFloats(strs ...string) ([]float64, error) {
+	f := make([]float64, len(strs))
+	for i, str := range strs {
+		var err error
+		if f[i], err = strconv.ParseFloat(str, 64); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// ParseDateString - parse a date expression to Time, the current time
+// if dt is empty and the zero Time if it is invalid
+//
+// Deprecated: use ParseDate, which reports invalid dates.
 func ParseDateString(dt string) time.Time {
 	if dt == "" {
 		return time.Now()
 	}
-	t, _ := time.Parse("2006-01-02 15:04", dt+"0000-01-01 00:00"[len(dt):])
+	t, _ := ParseDate(dt)
 	return t
 }
 
-func getPrecision(symbol string) int {
-	var precision int
-	precision = 2
-	if strings.Contains(strings.ToUpper(symbol), "BTC") ||
-		strings.Contains(strings.ToUpper(symbol), "ETH") ||
-		strings.Contains(strings.ToUpper(symbol), "USD") {
-		precision = 8
+// csvDateFormat - datetime column of the csv formats, with the offset
+// so files don't depend on the time zone of the machine that wrote them
+const csvDateFormat = "2006-01-02 15:04 -07:00"
+
+// csvDateHeader - name of the datetime column, which records the bar
+// time convention
+func csvDateHeader(b BarTime) string {
+	if b == BarClose {
+		return "closetime"
+	}
+	return "datetime"
+}
+
+// csvBarTime - the bar time convention recorded in column col of header
+func csvBarTime(header string, col int) BarTime {
+	cols := strings.Split(strings.TrimRight(header, "\r"), ",")
+	if col < len(cols) && cols[col] == "closetime" {
+		return BarClose
+	}
+	return BarOpen
+}
+
+// parseCSVDate - parse a csv datetime with format, or if format is
+// empty with csvDateFormat or the zoneless format of earlier versions.
+// The result is in UTC.
+func parseCSVDate(format, s string) (time.Time, error) {
+	if strings.TrimSpace(format) != "" {
+		t, err := time.Parse(format, s)
+		return t.UTC(), err
+	}
+	t, err := time.Parse(csvDateFormat, s)
+	if err != nil {
+		var err2 error
+		if t, err2 = time.Parse("2006-01-02 15:04", s); err2 != nil {
+			return t, err
+		}
+	}
+	return t.UTC(), nil
+}
+
+// In - q with its dates in loc, for output in an exchange or user time
+// zone. The dates of every Quote from this package are in UTC.
+func (q Quote) In(loc *time.Location) Quote {
+	dates := make([]time.Time, len(q.Date))
+	for i, d := range q.Date {
+		dates[i] = d.In(loc)
+	}
+	q.Date = dates
+	return q
+}
+
+// Adjusted - q with its adjusted prices and volume as the main columns,
+// for output that has room for one set of prices, and without the Adj
+// columns. Columns q has no adjusted values for are left raw.
+func (q Quote) Adjusted() Quote {
+	main := []*[]float64{&q.Open, &q.High, &q.Low, &q.Close, &q.Volume}
+	for i, col := range q.extras()[:5] {
+		if *col != nil {
+			*main[i] = *col
+			*col = nil
+			q.Exact = nil
+		}
+	}
+	return q
+}
+
+// Adjusted - each Quote in q with its adjusted prices as the main columns
+func (q Quotes) Adjusted() Quotes {
+	quotes := make(Quotes, len(q))
+	for i := range q {
+		quotes[i] = q[i].Adjusted()
 	}
-	return precision
+	return quotes
+}
+
+// In - each Quote in q with its dates in loc
+func (q Quotes) In(loc *time.Location) Quotes {
+	quotes := make(Quotes, len(q))
+	for i := range q {
+		quotes[i] = q[i].In(loc)
+	}
+	return quotes
 }
 
 // CSV - convert Quote structure to csv string
 func (q Quote) CSV() string {
 
-	precision := getPrecision(q.Symbol)
+	extra := q.extraIndexes()
 
 	var buffer bytes.Buffer
-	buffer.WriteString("datetime,open,high,low,close,volume\n")
+	buffer.WriteString(csvDateHeader(q.BarTime) + ",open,high,low,close,volume" + csvExtraHeader(extra) + "\n")
 	for bar := range q.Close {
-		str := fmt.Sprintf("%s,%.*f,%.*f,%.*f,%.*f,%.*f\n", q.Date[bar].Format("2006-01-02 15:04"),
-			precision, q.Open[bar], precision, q.High[bar], precision, q.Low[bar], precision, q.Close[bar], precision, q.Volume[bar])
+		f := q.priceFields(bar)
+		str := fmt.Sprintf("%s,%s%s\n", q.Date[bar].Format(csvDateFormat), strings.Join(f[:], ","),
+			q.csvExtraValues(extra, bar))
 		buffer.WriteString(str)
 	}
 	return buffer.String()
@@ -147,8 +364,6 @@ func (q Quote) CSV() string {
 // Highstock - convert Quote structure to Highstock json format
 func (q Quote) Highstock() string {
 
-	precision := getPrecision(q.Symbol)
-
 	var buffer bytes.Buffer
 	buffer.WriteString("[\n")
 	for bar := range q.Close {
@@ -156,8 +371,9 @@ func (q Quote) Highstock() string {
 		if bar == len(q.Close)-1 {
 			comma = ""
 		}
-		str := fmt.Sprintf("[%d,%.*f,%.*f,%.*f,%.*f,%.*f]%s\n",
-			q.Date[bar].UnixNano()/1000000, precision, q.Open[bar], precision, q.High[bar], precision, q.Low[bar], precision, q.Close[bar], precision, q.Volume[bar], comma)
+		f := q.priceFields(bar)
+		str := fmt.Sprintf("[%d,%s]%s\n",
+			q.Date[bar].UnixNano()/1000000, strings.Join(f[:], ","), comma)
 		buffer.WriteString(str)
 
 	}
@@ -168,13 +384,11 @@ func (q Quote) Highstock() string {
 // Amibroker - convert Quote structure to csv string
 func (q Quote) Amibroker() string {
 
-	precision := getPrecision(q.Symbol)
-
 	var buffer bytes.Buffer
 	buffer.WriteString("date,time,open,high,low,close,volume\n")
 	for bar := range q.Close {
-		str := fmt.Sprintf("%s,%s,%.*f,%.*f,%.*f,%.*f,%.*f\n", q.Date[bar].Format("2006-01-02"), q.Date[bar].Format("15:04"),
-			precision, q.Open[bar], precision, q.High[bar], precision, q.Low[bar], precision, q.Close[bar], precision, q.Volume[bar])
+		f := q.priceFields(bar)
+		str := fmt.Sprintf("%s,%s,%s\n", q.Date[bar].Format("2006-01-02"), q.Date[bar].Format("15:04"), strings.Join(f[:], ","))
 		buffer.WriteString(str)
 	}
 	return buffer.String()
@@ -221,47 +435,69 @@ func (q Quote) WriteHighstock(filename string) error {
 
 // NewQuoteFromCSV - parse csv quote string into Quote structure
 func NewQuoteFromCSV(symbol, csv string) (Quote, error) {
+	return NewQuoteFromCSVDateFormat(symbol, csv, "")
+}
 
-	tmp := strings.Split(csv, "\n")
-	numrows := len(tmp)
-	q := NewQuote(symbol, numrows-1)
-
-	for row, bar := 1, 0; row < numrows; row, bar = row+1, bar+1 {
-		line := strings.Split(tmp[row], ",")
-		if len(line) != 6 {
-			break
-		}
-		q.Date[bar], _ = time.Parse("2006-01-02 15:04", line[0])
-		q.Open[bar], _ = strconv.ParseFloat(line[1], 64)
-		q.High[bar], _ = strconv.ParseFloat(line[2], 64)
-		q.Low[bar], _ = strconv.ParseFloat(line[3], 64)
-		q.Close[bar], _ = strconv.ParseFloat(line[4], 64)
-		q.Volume[bar], _ = strconv.ParseFloat(line[5], 64)
-	}
-	return q, nil
+// NewQuoteFromCSVExact - NewQuoteFromCSV keeping the prices and volumes
+// exactly as written in Quote.Exact
+func NewQuoteFromCSVExact(symbol, csv string) (Quote, error) {
+	return parseQuoteCSV(symbol, csv, "", true)
 }
 
 // NewQuoteFromCSVDateFormat - parse csv quote string into Quote structure
-// with specified DateTime format
+// with specified DateTime format, dates without a zone are taken as UTC
 func NewQuoteFromCSVDateFormat(symbol, csv string, format string) (Quote, error) {
+	return parseQuoteCSV(symbol, csv, format, false)
+}
+
+// parseQuoteCSV - parse a csv quote string, keeping the exact prices
+// if exact is set
+func parseQuoteCSV(symbol, csv, format string, exact bool) (Quote, error) {
 
 	tmp := strings.Split(csv, "\n")
 	numrows := len(tmp)
-	q := NewQuote("", numrows-1)
-
-	if len(strings.TrimSpace(format)) == 0 {
-		format = "2006-01-02 15:04"
+	q := NewQuote(symbol, numrows-1)
+	q.BarTime = csvBarTime(tmp[0], 0)
+	extra, err := csvExtraColumns(tmp[0], 6)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+	cols := q.extras()
+	for _, i := range extra {
+		*cols[i] = make([]float64, numrows-1)
+	}
+	if exact {
+		q.Exact = make([]ExactBar, numrows-1)
 	}
 
-	for row, bar := 1, 0; row < numrows; row, bar = row+1, bar+1 {
-		line := strings.Split(tmp[row], ",")
-		q.Date[bar], _ = time.Parse(format, line[0])
-		q.Open[bar], _ = strconv.ParseFloat(line[1], 64)
-		q.High[bar], _ = strconv.ParseFloat(line[2], 64)
-		q.Low[bar], _ = strconv.ParseFloat(line[3], 64)
-		q.Close[bar], _ = strconv.ParseFloat(line[4], 64)
-		q.Volume[bar], _ = strconv.ParseFloat(line[5], 64)
+	bar := 0
+	for row := 1; row < numrows; row++ {
+		if strings.TrimSpace(tmp[row]) == "" {
+			continue
+		}
+		line := strings.Split(strings.TrimRight(tmp[row], "\r"), ",")
+		if len(line) != 6+len(extra) {
+			return NewQuote("", 0), fmt.Errorf("csv row %d: expected %d fields, got %d", row+1, 6+len(extra), len(line))
+		}
+		var err error
+		if q.Date[bar], err = parseCSVDate(format, line[0]); err != nil {
+			return NewQuote("", 0), fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		f, err := parseFloats(line[1:]...)
+		if err != nil {
+			return NewQuote("", 0), fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		q.Open[bar], q.High[bar], q.Low[bar], q.Close[bar], q.Volume[bar] = f[0], f[1], f[2], f[3], f[4]
+		q.notePrecision(line[1:], extra)
+		for c, i := range extra {
+			(*cols[i])[bar] = f[5+c]
+		}
+		if exact {
+			q.Exact[bar] = ExactBar{Decimal(line[1]), Decimal(line[2]), Decimal(line[3]), Decimal(line[4]), Decimal(line[5])}
+		}
+		bar++
 	}
+	q.truncate(bar)
 	return q, nil
 }
 
@@ -312,7 +548,7 @@ func NewQuoteFromJSON(jsn string) (Quote, error) {
 	if err != nil {
 		return q, err
 	}
-	return q, nil
+	return q.In(time.UTC), nil
 }
 
 // NewQuoteFromJSONFile - parse json quote string into Quote structure
@@ -329,14 +565,20 @@ func (q Quotes) CSV() string {
 
 	var buffer bytes.Buffer
 
-	buffer.WriteString("symbol,datetime,open,high,low,close,volume\n")
+	var barTime BarTime
+	if len(q) > 0 {
+		barTime = q[0].BarTime
+	}
+	extra := q.extraIndexes()
+	buffer.WriteString("symbol," + csvDateHeader(barTime) + ",open,high,low,close,volume" + csvExtraHeader(extra) + "\n")
 
 	for sym := 0; sym < len(q); sym++ {
 		quote := q[sym]
-		precision := getPrecision(quote.Symbol)
 		for bar := range quote.Close {
-			str := fmt.Sprintf("%s,%s,%.*f,%.*f,%.*f,%.*f,%.*f\n",
-				quote.Symbol, quote.Date[bar].Format("2006-01-02 15:04"), precision, quote.Open[bar], precision, quote.High[bar], precision, quote.Low[bar], precision, quote.Close[bar], precision, quote.Volume[bar])
+			f := quote.priceFields(bar)
+			str := fmt.Sprintf("%s,%s,%s%s\n",
+				quote.Symbol, quote.Date[bar].Format(csvDateFormat), strings.Join(f[:], ","),
+				quote.csvExtraValues(extra, bar))
 			buffer.WriteString(str)
 		}
 	}
@@ -344,6 +586,27 @@ func (q Quotes) CSV() string {
 	return buffer.String()
 }
 
+// extraIndexes - extraColumns indexes of the optional columns every Quote in q has
+func (q Quotes) extraIndexes() []int {
+	if len(q) == 0 {
+		return nil
+	}
+	idx := q[0].extraIndexes()
+	for _, quote := range q[1:] {
+		has := quote.extraIndexes()
+		var both []int
+		for _, i := range idx {
+			for _, j := range has {
+				if i == j {
+					both = append(both, i)
+				}
+			}
+		}
+		idx = both
+	}
+	return idx
+}
+
 // Highstock - convert Quotes structure to Highstock json format
 func (q Quotes) Highstock() string {
 
@@ -353,7 +616,6 @@ func (q Quotes) Highstock() string {
 
 	for sym := 0; sym < len(q); sym++ {
 		quote := q[sym]
-		precision := getPrecision(quote.Symbol)
 		for bar := range quote.Close {
 			comma := ","
 			if bar == len(quote.Close)-1 {
@@ -362,8 +624,9 @@ func (q Quotes) Highstock() string {
 			if bar == 0 {
 				buffer.WriteString(fmt.Sprintf("\"%s\":[\n", quote.Symbol))
 			}
-			str := fmt.Sprintf("[%d,%.*f,%.*f,%.*f,%.*f,%.*f]%s\n",
-				quote.Date[bar].UnixNano()/1000000, precision, quote.Open[bar], precision, quote.High[bar], precision, quote.Low[bar], precision, quote.Close[bar], precision, quote.Volume[bar], comma)
+			f := quote.priceFields(bar)
+			str := fmt.Sprintf("[%d,%s]%s\n",
+				quote.Date[bar].UnixNano()/1000000, strings.Join(f[:], ","), comma)
 			buffer.WriteString(str)
 		}
 		if sym < len(q)-1 {
@@ -387,10 +650,10 @@ func (q Quotes) Amibroker() string {
 
 	for sym := 0; sym < len(q); sym++ {
 		quote := q[sym]
-		precision := getPrecision(quote.Symbol)
 		for bar := range quote.Close {
-			str := fmt.Sprintf("%s,%s,%s,%.*f,%.*f,%.*f,%.*f,%.*f\n",
-				quote.Symbol, quote.Date[bar].Format("2006-01-02"), quote.Date[bar].Format("15:04"), precision, quote.Open[bar], precision, quote.High[bar], precision, quote.Low[bar], precision, quote.Close[bar], precision, quote.Volume[bar])
+			f := quote.priceFields(bar)
+			str := fmt.Sprintf("%s,%s,%s,%s\n",
+				quote.Symbol, quote.Date[bar].Format("2006-01-02"), quote.Date[bar].Format("15:04"), strings.Join(f[:], ","))
 			buffer.WriteString(str)
 		}
 	}
@@ -418,33 +681,72 @@ func (q Quotes) WriteAmibroker(filename string) error {
 	return ioutil.WriteFile(filename, ba, 0644)
 }
 
-// NewQuotesFromCSV - parse csv quote string into Quotes array
+// NewQuotesFromCSV - parse csv quote string into Quotes array, in the
+// order each symbol first appears
 func NewQuotesFromCSV(csv string) (Quotes, error) {
+	return parseQuotesCSV(csv, false)
+}
+
+// NewQuotesFromCSVExact - NewQuotesFromCSV keeping the prices and
+// volumes exactly as written in Quote.Exact
+func NewQuotesFromCSVExact(csv string) (Quotes, error) {
+	return parseQuotesCSV(csv, true)
+}
+
+// parseQuotesCSV - parse a csv quotes string, keeping the exact prices
+// if exact is set
+func parseQuotesCSV(csv string, exact bool) (Quotes, error) {
 
 	quotes := Quotes{}
 	tmp := strings.Split(csv, "\n")
 	numrows := len(tmp)
 
+	barTime := csvBarTime(tmp[0], 1)
+	extra, err := csvExtraColumns(tmp[0], 7)
+	if err != nil {
+		return Quotes{}, err
+	}
 	var index = make(map[string]int)
-	for idx := 1; idx < numrows; idx++ {
-		sym := strings.Split(tmp[idx], ",")[0]
-		index[sym]++
-	}
-
-	row := 1
-	for sym, len := range index {
-		q := NewQuote(sym, len)
-		for bar := 0; bar < len; bar++ {
-			line := strings.Split(tmp[row], ",")
-			q.Date[bar], _ = time.Parse("2006-01-02 15:04", line[1])
-			q.Open[bar], _ = strconv.ParseFloat(line[2], 64)
-			q.High[bar], _ = strconv.ParseFloat(line[3], 64)
-			q.Low[bar], _ = strconv.ParseFloat(line[4], 64)
-			q.Close[bar], _ = strconv.ParseFloat(line[5], 64)
-			q.Volume[bar], _ = strconv.ParseFloat(line[6], 64)
-			row++
-		}
-		quotes = append(quotes, q)
+	for row := 1; row < numrows; row++ {
+		if strings.TrimSpace(tmp[row]) == "" {
+			continue
+		}
+		line := strings.Split(strings.TrimRight(tmp[row], "\r"), ",")
+		if len(line) != 7+len(extra) {
+			return Quotes{}, fmt.Errorf("csv row %d: expected %d fields, got %d", row+1, 7+len(extra), len(line))
+		}
+		d, err := parseCSVDate("", line[1])
+		if err != nil {
+			return Quotes{}, fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		f, err := parseFloats(line[2:]...)
+		if err != nil {
+			return Quotes{}, fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+
+		sym := line[0]
+		idx, ok := index[sym]
+		if !ok {
+			idx = len(quotes)
+			index[sym] = idx
+			quotes = append(quotes, NewQuote(sym, 0))
+			quotes[idx].BarTime = barTime
+		}
+		q := &quotes[idx]
+		q.Date = append(q.Date, d)
+		q.Open = append(q.Open, f[0])
+		q.High = append(q.High, f[1])
+		q.Low = append(q.Low, f[2])
+		q.Close = append(q.Close, f[3])
+		q.Volume = append(q.Volume, f[4])
+		q.notePrecision(line[2:], extra)
+		cols := q.extras()
+		for c, i := range extra {
+			*cols[i] = append(*cols[i], f[5+c])
+		}
+		if exact {
+			q.Exact = append(q.Exact, ExactBar{Decimal(line[2]), Decimal(line[3]), Decimal(line[4]), Decimal(line[5]), Decimal(line[6])})
+		}
 	}
 	return quotes, nil
 }
@@ -494,7 +796,7 @@ func NewQuotesFromJSON(jsn string) (Quotes, error) {
 	if err != nil {
 		return quotes, err
 	}
-	return quotes, nil
+	return quotes.In(time.UTC), nil
 }
 
 // NewQuotesFromJSONFile - parse json quote string into Quote structure
@@ -508,81 +810,95 @@ func NewQuotesFromJSONFile(filename string) (Quotes, error) {
 
 // NewQuoteFromYahoo - Yahoo historical prices for a symbol
 func NewQuoteFromYahoo(symbol, startDate, endDate string, period Period, adjustQuote bool) (Quote, error) {
+	return NewQuoteFromYahooContext(context.Background(), symbol, startDate, endDate, period, adjustQuote)
+}
 
-	if period != Daily {
-		Log.Printf("Yahoo intraday data no longer supported\n")
-		return NewQuote("", 0), errors.New("Yahoo intraday data no longer supported")
-	}
-
-	from := ParseDateString(startDate)
-	to := ParseDateString(endDate)
+// NewQuoteFromYahooContext - NewQuoteFromYahoo with a context for cancellation
+func NewQuoteFromYahooContext(ctx context.Context, symbol, startDate, endDate string, period Period, adjustQuote bool) (Quote, error) {
+	return defaultClient().QuoteFromYahoo(ctx, symbol, startDate, endDate, period, adjustQuote)
+}
 
-	client := &http.Client{
-		Timeout: ClientTimeout,
-	}
+// QuoteFromYahoo - Yahoo historical prices for a symbol, Yahoo's own
+// prices in Open..Close, which are split adjusted, and the prices adjusted
+// for dividends too in AdjOpen..AdjClose. With adjustQuote the adjusted
+// prices are moved to Open..Close, as Quote.Adjusted does.
+func (c *Client) QuoteFromYahoo(ctx context.Context, symbol, startDate, endDate string, period Period, adjustQuote bool) (Quote, error) {
 
-	initReq, err := http.NewRequest("GET", "https://finance.yahoo.com", nil)
+	from, to, err := parseDates(startDate, endDate)
 	if err != nil {
 		return NewQuote("", 0), err
 	}
-	initReq.Header.Set("User-Agent", "Mozilla/5.0 (X11; U; Linux i686) Gecko/20071127 Firefox/2.0.0.11")
-	resp, _ := client.Do(initReq)
 
-	url := fmt.Sprintf(
-		"https://query1.finance.yahoo.com/v7/finance/download/%s?period1=%d&period2=%d&interval=1d&events=history&corsDomain=finance.yahoo.com",
-		symbol,
-		from.Unix(),
-		to.Unix())
-	resp, err = client.Get(url)
+	q, err := c.yahoo(ctx, symbol, from, to, period)
+	if err != nil || !adjustQuote {
+		return q, err
+	}
+	return q.Adjusted(), nil
+}
+
+func (c *Client) yahoo(ctx context.Context, symbol string, from, to time.Time, period Period) (Quote, error) {
+
+	period, err := checkPeriod("yahoo", symbol, period)
 	if err != nil {
-		Log.Printf("symbol '%s' not found\n", symbol)
+		c.log(LevelWarn, "intraday data no longer supported", F("source", "yahoo"), F("symbol", symbol))
 		return NewQuote("", 0), err
 	}
-	defer resp.Body.Close()
 
-	var csvdata [][]string
-	reader := csv.NewReader(resp.Body)
-	csvdata, err = reader.ReadAll()
+	csvdata, err := c.yahooCSV(ctx, symbol, from, to, "history", 7)
 	if err != nil {
-		Log.Printf("bad data for symbol '%s'\n", symbol)
 		return NewQuote("", 0), err
 	}
 
 	numrows := len(csvdata) - 1
+	if numrows < 0 {
+		numrows = 0
+	}
 	quote := NewQuote(symbol, numrows)
+	for _, col := range quote.extras()[:4] {
+		*col = make([]float64, numrows)
+	}
 
+	bar := 0
 	for row := 1; row < len(csvdata); row++ {
 
+		// yahoo reports missing bars as a row of nulls
+		if csvdata[row][1] == "null" {
+			continue
+		}
+
 		// Parse row of data
-		d, _ := time.Parse("2006-01-02", csvdata[row][0])
-		o, _ := strconv.ParseFloat(csvdata[row][1], 64)
-		h, _ := strconv.ParseFloat(csvdata[row][2], 64)
-		l, _ := strconv.ParseFloat(csvdata[row][3], 64)
-		c, _ := strconv.ParseFloat(csvdata[row][4], 64)
-		a, _ := strconv.ParseFloat(csvdata[row][5], 64)
-		v, _ := strconv.ParseFloat(csvdata[row][6], 64)
+		d, err := sessionDate("yahoo", csvdata[row][0])
+		if err != nil {
+			return NewQuote("", 0), malformed("yahoo", symbol, err)
+		}
+		f, err := parseFloats(csvdata[row][1:]...)
+		if err != nil {
+			return NewQuote("", 0), malformed("yahoo", symbol, err)
+		}
+		o, h, l, c, a, v := f[0], f[1], f[2], f[3], f[4], f[5]
 
-		quote.Date[row-1] = d
+		quote.Date[bar] = d
+		quote.Open[bar] = o
+		quote.High[bar] = h
+		quote.Low[bar] = l
+		quote.Close[bar] = c
+		quote.Volume[bar] = v
 
 		// Adjustment ratio
-		if adjustQuote {
-			quote.Open[row-1] = o
-			quote.High[row-1] = h
-			quote.Low[row-1] = l
-			quote.Close[row-1] = a
-		} else {
-			ratio := c / a
-			quote.Open[row-1] = o * ratio
-			quote.High[row-1] = h * ratio
-			quote.Low[row-1] = l * ratio
-			quote.Close[row-1] = c
+		ratio := 1.0
+		if c != 0 {
+			ratio = a / c
 		}
-
-		quote.Volume[row-1] = v
-
+		quote.AdjOpen[bar] = o * ratio
+		quote.AdjHigh[bar] = h * ratio
+		quote.AdjLow[bar] = l * ratio
+		quote.AdjClose[bar] = a
+		bar++
 	}
 
-	return quote, nil
+	quote.truncate(bar)
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "yahoo", symbol)
+	return c.stampSession(quote, "yahoo"), nil
 }
 
 /*
@@ -647,108 +963,209 @@ func NewQuoteFromYahoo(symbol, startDate, endDate string, period Period, adjustQ
 }
 */
 
-// NewQuotesFromYahoo - create a list of prices from symbols in file
-func NewQuotesFromYahoo(filename, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+// yahooCSV - the rows of a yahoo download, with the header, for events
+// history, div or split
+func (c *Client) yahooCSV(ctx context.Context, symbol string, from, to time.Time, events string, fields int) ([][]string, error) {
 
-	quotes := Quotes{}
-	inFile, err := os.Open(filename)
+	initReq, err := http.NewRequestWithContext(ctx, "GET", c.HTTP.baseURL("yahoo-init"), nil)
 	if err != nil {
-		return quotes, err
+		return nil, err
+	}
+	initReq.Header.Set("User-Agent", "Mozilla/5.0 (X11; U; Linux i686) Gecko/20071127 Firefox/2.0.0.11")
+	resp, err := c.do(initReq, "yahoo", symbol)
+	if err == nil {
+		resp.Body.Close()
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
 	}
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	scanner.Split(bufio.ScanLines)
 
-	for scanner.Scan() {
-		sym := scanner.Text()
-		quote, err := NewQuoteFromYahoo(sym, startDate, endDate, period, adjustQuote)
-		if err == nil {
-			quotes = append(quotes, quote)
-		}
-		time.Sleep(Delay * time.Millisecond)
+	url := fmt.Sprintf(
+		"%s/v7/finance/download/%s?period1=%d&period2=%d&interval=1d&events=%s&corsDomain=finance.yahoo.com",
+		c.HTTP.baseURL("yahoo"),
+		symbol,
+		from.Unix(),
+		to.Unix(),
+		events)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
-	return quotes, nil
+	resp, err = c.do(req, "yahoo", symbol)
+	if err != nil {
+		c.logError("download failed", "yahoo", symbol, err)
+		return nil, ctxErr(ctx, err)
+	}
+	defer resp.Body.Close()
+
+	if err = checkStatus(resp, "yahoo", symbol); err != nil {
+		c.logError("download failed", "yahoo", symbol, err)
+		return nil, err
+	}
+
+	reader := csv.NewReader(resp.Body)
+	reader.FieldsPerRecord = fields
+	csvdata, err := reader.ReadAll()
+	if err != nil {
+		c.logError("bad data", "yahoo", symbol, err)
+		return nil, malformed("yahoo", symbol, ctxErr(ctx, err))
+	}
+	return csvdata, nil
+}
+
+// NewQuotesFromYahoo - create a list of prices from symbols in file
+// symbols that fail to download are left out and reported in a *DownloadError
+func NewQuotesFromYahoo(filename, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+	return NewQuotesFromYahooContext(context.Background(), filename, startDate, endDate, period, adjustQuote)
+}
+
+// NewQuotesFromYahooContext - NewQuotesFromYahoo with a context for cancellation
+func NewQuotesFromYahooContext(ctx context.Context, filename, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+	return defaultClient().QuotesFromYahoo(ctx, filename, startDate, endDate, period, adjustQuote)
+}
+
+// QuotesFromYahoo - create a list of prices from symbols in file
+func (c *Client) QuotesFromYahoo(ctx context.Context, filename, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+
+	symbols, err := symbolsFromFile(filename)
+	if err != nil {
+		return Quotes{}, err
+	}
+	return c.QuotesFromYahooSyms(ctx, symbols, startDate, endDate, period, adjustQuote)
 }
 
 // NewQuotesFromYahooSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromYahooSyms(symbols []string, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+	return NewQuotesFromYahooSymsContext(context.Background(), symbols, startDate, endDate, period, adjustQuote)
+}
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromYahoo(symbol, startDate, endDate, period, adjustQuote)
-		if err == nil {
-			quotes = append(quotes, quote)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromYahooSymsContext - NewQuotesFromYahooSyms with a context for cancellation
+func NewQuotesFromYahooSymsContext(ctx context.Context, symbols []string, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+	return defaultClient().QuotesFromYahooSyms(ctx, symbols, startDate, endDate, period, adjustQuote)
+}
+
+// QuotesFromYahooSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromYahooSyms(ctx context.Context, symbols []string, startDate, endDate string, period Period, adjustQuote bool) (Quotes, error) {
+
+	results, err := c.downloadEach(ctx, "yahoo", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromYahoo(ctx, symbol, startDate, endDate, period, adjustQuote)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
 }
 
-func tiingoDaily(symbol string, from, to time.Time, token string) (Quote, error) {
+// tiingoPrice - a day of tiingo end of day prices
+type tiingoPrice struct {
+	AdjClose    float64 `json:"adjClose"`
+	AdjHigh     float64 `json:"adjHigh"`
+	AdjLow      float64 `json:"adjLow"`
+	AdjOpen     float64 `json:"adjOpen"`
+	AdjVolume   int64   `json:"adjVolume"`
+	Close       float64 `json:"close"`
+	Date        string  `json:"date"`
+	DivCash     float64 `json:"divCash"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Open        float64 `json:"open"`
+	SplitFactor float64 `json:"splitFactor"`
+	Volume      int64   `json:"volume"`
+}
 
-	type tquote struct {
-		AdjClose    float64 `json:"adjClose"`
-		AdjHigh     float64 `json:"adjHigh"`
-		AdjLow      float64 `json:"adjLow"`
-		AdjOpen     float64 `json:"adjOpen"`
-		AdjVolume   int64   `json:"adjVolume"`
-		Close       float64 `json:"close"`
-		Date        string  `json:"date"`
-		DivCash     float64 `json:"divCash"`
-		High        float64 `json:"high"`
-		Low         float64 `json:"low"`
-		Open        float64 `json:"open"`
-		SplitFactor float64 `json:"splitFactor"`
-		Volume      int64   `json:"volume"`
+// date - the start of the session date of p
+func (p tiingoPrice) date() (time.Time, error) {
+	if len(p.Date) < 10 {
+		return time.Time{}, fmt.Errorf("bad date '%s'", p.Date)
 	}
+	return sessionDate("tiingo", p.Date[0:10])
+}
 
-	var tiingo []tquote
+// tiingoPrices - tiingo end of day prices for symbol
+func (c *Client) tiingoPrices(ctx context.Context, symbol string, from, to time.Time) ([]tiingoPrice, error) {
+
+	var tiingo []tiingoPrice
 
 	url := fmt.Sprintf(
-		"https://api.tiingo.com/tiingo/daily/%s/prices?startDate=%s&endDate=%s",
+		"%s/tiingo/daily/%s/prices?startDate=%s&endDate=%s",
+		c.HTTP.baseURL("tiingo"),
 		symbol,
 		url.QueryEscape(from.Format("2006-1-2")),
 		url.QueryEscape(to.Format("2006-1-2")))
 
-	client := &http.Client{Timeout: ClientTimeout}
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
-	resp, err := client.Do(req)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.TiingoToken))
+	resp, err := c.do(req, "tiingo", symbol)
 
 	if err != nil {
-		Log.Printf("tiingo error: %v\n", err)
-		return NewQuote("", 0), err
+		c.logError("download failed", "tiingo", symbol, err)
+		return nil, ctxErr(ctx, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		contents, _ := ioutil.ReadAll(resp.Body)
-		err = json.Unmarshal(contents, &tiingo)
-		if err != nil {
-			Log.Printf("tiingo error: %v\n", err)
-			return NewQuote("", 0), err
-		}
-	} else if resp.StatusCode == http.StatusNotFound {
-		Log.Printf("symbol '%s' not found\n", symbol)
+	if err = checkStatus(resp, "tiingo", symbol); err != nil {
+		c.logError("download failed", "tiingo", symbol, err)
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, netErr(ctx, "tiingo", symbol, err)
+	}
+	err = json.Unmarshal(contents, &tiingo)
+	if err != nil {
+		c.logError("download failed", "tiingo", symbol, err)
+		return nil, malformed("tiingo", symbol, err)
+	}
+	return tiingo, nil
+}
+
+// tiingoDaily - tiingo end of day prices, raw with the adjusted prices
+// and volume alongside
+func (c *Client) tiingoDaily(ctx context.Context, symbol string, from, to time.Time) (Quote, error) {
+
+	tiingo, err := c.tiingoPrices(ctx, symbol, from, to)
+	if err != nil {
 		return NewQuote("", 0), err
 	}
 
 	numrows := len(tiingo)
 	quote := NewQuote(symbol, numrows)
-
-	for bar := 0; bar < numrows; bar++ {
-		quote.Date[bar], _ = time.Parse("2006-01-02", tiingo[bar].Date[0:10])
-		quote.Open[bar] = tiingo[bar].AdjOpen
-		quote.High[bar] = tiingo[bar].AdjHigh
-		quote.Low[bar] = tiingo[bar].AdjLow
-		quote.Close[bar] = tiingo[bar].AdjClose
-		quote.Volume[bar] = float64(tiingo[bar].Volume)
+	for _, col := range quote.extras()[:5] {
+		*col = make([]float64, numrows)
 	}
 
-	return quote, nil
+	for bar := 0; bar < numrows; bar++ {
+		p := tiingo[bar]
+		quote.Date[bar], err = p.date()
+		if err != nil {
+			return NewQuote("", 0), malformed("tiingo", symbol, err)
+		}
+		quote.Open[bar] = p.Open
+		quote.High[bar] = p.High
+		quote.Low[bar] = p.Low
+		quote.Close[bar] = p.Close
+		quote.Volume[bar] = float64(p.Volume)
+		quote.AdjOpen[bar] = p.AdjOpen
+		quote.AdjHigh[bar] = p.AdjHigh
+		quote.AdjLow[bar] = p.AdjLow
+		quote.AdjClose[bar] = p.AdjClose
+		quote.AdjVolume[bar] = float64(p.AdjVolume)
+	}
+
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "tiingo", symbol)
+	return c.stampSession(quote, "tiingo"), nil
 }
 
-func tiingoCrypto(symbol string, from, to time.Time, period Period, token string) (Quote, error) {
+func (c *Client) tiingoCrypto(ctx context.Context, symbol string, from, to time.Time, period Period) (Quote, error) {
+
+	period, err := checkPeriod("tiingo-crypto", symbol, period)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
 
 	resampleFreq := "1day"
 	switch period {
@@ -776,6 +1193,8 @@ func tiingoCrypto(symbol string, from, to time.Time, period Period, token string
 		resampleFreq = "12hour"
 	case Daily:
 		resampleFreq = "1day"
+	default:
+		return NewQuote("", 0), newError(ErrInvalidPeriod, "tiingo-crypto", symbol, fmt.Errorf("'%s' not supported", period))
 	}
 
 	type priceData struct {
@@ -799,125 +1218,201 @@ func tiingoCrypto(symbol string, from, to time.Time, period Period, token string
 	var crypto []cryptoData
 
 	url := fmt.Sprintf(
-		"https://api.tiingo.com/tiingo/crypto/prices?tickers=%s&startDate=%s&endDate=%s&resampleFreq=%s",
+		"%s/tiingo/crypto/prices?tickers=%s&startDate=%s&endDate=%s&resampleFreq=%s",
+		c.HTTP.baseURL("tiingo"),
 		symbol,
 		url.QueryEscape(from.Format("2006-1-2")),
 		url.QueryEscape(to.Format("2006-1-2")),
 		resampleFreq)
 
-	client := &http.Client{Timeout: ClientTimeout}
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
-	resp, err := client.Do(req)
-
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		Log.Printf("symbol '%s' not found\n", symbol)
 		return NewQuote("", 0), err
 	}
+	req.Header.Set("Authorization", fmt.Sprintf("Token %s", c.TiingoToken))
+	resp, err := c.do(req, "tiingo-crypto", symbol)
+
+	if err != nil {
+		c.logError("download failed", "tiingo-crypto", symbol, err)
+		return NewQuote("", 0), ctxErr(ctx, err)
+	}
 	defer resp.Body.Close()
 
-	contents, _ := ioutil.ReadAll(resp.Body)
+	if err = checkStatus(resp, "tiingo-crypto", symbol); err != nil {
+		c.logError("download failed", "tiingo-crypto", symbol, err)
+		return NewQuote("", 0), err
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return NewQuote("", 0), netErr(ctx, "tiingo-crypto", symbol, err)
+	}
 	err = json.Unmarshal(contents, &crypto)
 	if err != nil {
-		Log.Printf("tiingo crypto symbol '%s' error: %v\n", symbol, err)
-		return NewQuote("", 0), err
+		c.logError("download failed", "tiingo-crypto", symbol, err)
+		return NewQuote("", 0), malformed("tiingo-crypto", symbol, err)
 	}
 	if len(crypto) < 1 {
-		Log.Printf("tiingo crypto symbol '%s' No data returned", symbol)
-		return NewQuote("", 0), err
+		c.log(LevelWarn, "no data returned", F("source", "tiingo-crypto"), F("symbol", symbol))
+		return NewQuote("", 0), newError(ErrSymbolNotFound, "tiingo-crypto", symbol, nil)
 	}
 
 	numrows := len(crypto[0].PriceData)
 	quote := NewQuote(symbol, numrows)
+	quote.QuoteVolume = make([]float64, numrows)
+	quote.Trades = make([]float64, numrows)
 
 	for bar := 0; bar < numrows; bar++ {
-		quote.Date[bar], _ = time.Parse(time.RFC3339, crypto[0].PriceData[bar].Date)
+		quote.Date[bar], err = time.Parse(time.RFC3339, crypto[0].PriceData[bar].Date)
+		if err != nil {
+			return NewQuote("", 0), malformed("tiingo-crypto", symbol, err)
+		}
+		quote.Date[bar] = quote.Date[bar].UTC()
 		quote.Open[bar] = crypto[0].PriceData[bar].Open
 		quote.High[bar] = crypto[0].PriceData[bar].High
 		quote.Low[bar] = crypto[0].PriceData[bar].Low
 		quote.Close[bar] = crypto[0].PriceData[bar].Close
 		quote.Volume[bar] = float64(crypto[0].PriceData[bar].Volume)
+		quote.QuoteVolume[bar] = crypto[0].PriceData[bar].VolumeNotional
+		quote.Trades[bar] = crypto[0].PriceData[bar].TradesDone
 	}
 
-	return quote, nil
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "tiingo-crypto", symbol)
+	return c.stamp(quote, period), nil
 }
 
 // NewQuoteFromTiingo - Tiingo daily historical prices for a symbol
 func NewQuoteFromTiingo(symbol, startDate, endDate string, token string) (Quote, error) {
+	return NewQuoteFromTiingoContext(context.Background(), symbol, startDate, endDate, token)
+}
 
-	from := ParseDateString(startDate)
-	to := ParseDateString(endDate)
+// NewQuoteFromTiingoContext - NewQuoteFromTiingo with a context for
+// cancellation. The adjusted prices are in Open..Volume, as they always
+// were, use Client.QuoteFromTiingo for the raw prices too.
+func NewQuoteFromTiingoContext(ctx context.Context, symbol, startDate, endDate string, token string) (Quote, error) {
+	c := defaultClient()
+	c.TiingoToken = token
+	q, err := c.QuoteFromTiingo(ctx, symbol, startDate, endDate)
+	if err != nil {
+		return q, err
+	}
+	return q.Adjusted(), nil
+}
+
+// QuoteFromTiingo - Tiingo daily historical prices for a symbol, raw
+// prices in Open..Volume and adjusted ones in AdjOpen..AdjVolume
+func (c *Client) QuoteFromTiingo(ctx context.Context, symbol, startDate, endDate string) (Quote, error) {
+
+	from, to, err := parseDates(startDate, endDate)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
 
-	return tiingoDaily(symbol, from, to, token)
+	return c.tiingoDaily(ctx, symbol, from, to)
 }
 
 // NewQuoteFromTiingoCrypto - Tiingo crypto historical prices for a symbol
 func NewQuoteFromTiingoCrypto(symbol, startDate, endDate string, period Period, token string) (Quote, error) {
+	return NewQuoteFromTiingoCryptoContext(context.Background(), symbol, startDate, endDate, period, token)
+}
 
-	from := ParseDateString(startDate)
-	to := ParseDateString(endDate)
+// NewQuoteFromTiingoCryptoContext - NewQuoteFromTiingoCrypto with a context for cancellation
+func NewQuoteFromTiingoCryptoContext(ctx context.Context, symbol, startDate, endDate string, period Period, token string) (Quote, error) {
+	c := defaultClient()
+	c.TiingoToken = token
+	return c.QuoteFromTiingoCrypto(ctx, symbol, startDate, endDate, period)
+}
+
+// QuoteFromTiingoCrypto - Tiingo crypto historical prices for a symbol
+func (c *Client) QuoteFromTiingoCrypto(ctx context.Context, symbol, startDate, endDate string, period Period) (Quote, error) {
 
-	return tiingoCrypto(symbol, from, to, period, token)
+	from, to, err := parseDates(startDate, endDate)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+
+	return c.tiingoCrypto(ctx, symbol, from, to, period)
 }
 
 // NewQuotesFromTiingoSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromTiingoSyms(symbols []string, startDate, endDate string, token string) (Quotes, error) {
+	return NewQuotesFromTiingoSymsContext(context.Background(), symbols, startDate, endDate, token)
+}
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromTiingo(symbol, startDate, endDate, token)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + symbol)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromTiingoSymsContext - NewQuotesFromTiingoSyms with a context for cancellation
+func NewQuotesFromTiingoSymsContext(ctx context.Context, symbols []string, startDate, endDate string, token string) (Quotes, error) {
+	c := defaultClient()
+	c.TiingoToken = token
+	quotes, err := c.QuotesFromTiingoSyms(ctx, symbols, startDate, endDate)
+	return quotes.Adjusted(), err
+}
+
+// QuotesFromTiingoSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromTiingoSyms(ctx context.Context, symbols []string, startDate, endDate string) (Quotes, error) {
+
+	results, err := c.downloadEach(ctx, "tiingo", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromTiingo(ctx, symbol, startDate, endDate)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
+}
+
+// NewQuotesFromTiingoCryptoSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
+func NewQuotesFromTiingoCryptoSyms(symbols []string, startDate, endDate string, period Period, token string) (Quotes, error) {
+	return NewQuotesFromTiingoCryptoSymsContext(context.Background(), symbols, startDate, endDate, period, token)
+}
+
+// NewQuotesFromTiingoCryptoSymsContext - NewQuotesFromTiingoCryptoSyms with a context for cancellation
+func NewQuotesFromTiingoCryptoSymsContext(ctx context.Context, symbols []string, startDate, endDate string, period Period, token string) (Quotes, error) {
+	c := defaultClient()
+	c.TiingoToken = token
+	return c.QuotesFromTiingoCryptoSyms(ctx, symbols, startDate, endDate, period)
 }
 
-// NewQuotesFromTiingoCryptoSyms - create a list of prices from symbols in string array
-func NewQuotesFromTiingoCryptoSyms(symbols []string, startDate, endDate string, period Period, token string) (Quotes, error) {
+// QuotesFromTiingoCryptoSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromTiingoCryptoSyms(ctx context.Context, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromTiingoCrypto(symbol, startDate, endDate, period, token)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + symbol)
-		}
-		time.Sleep(Delay * time.Millisecond)
+	results, err := c.downloadEach(ctx, "tiingo-crypto", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromTiingoCrypto(ctx, symbol, startDate, endDate, period)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
 }
 
 // NewQuoteFromCoinbase - Coinbase Pro historical prices for a symbol
 func NewQuoteFromCoinbase(symbol, startDate, endDate string, period Period) (Quote, error) {
+	return NewQuoteFromCoinbaseContext(context.Background(), symbol, startDate, endDate, period)
+}
 
-	start := ParseDateString(startDate) //.In(time.Now().Location())
-	end := ParseDateString(endDate)     //.In(time.Now().Location())
+// NewQuoteFromCoinbaseContext - NewQuoteFromCoinbase with a context for cancellation
+func NewQuoteFromCoinbaseContext(ctx context.Context, symbol, startDate, endDate string, period Period) (Quote, error) {
+	return defaultClient().QuoteFromCoinbase(ctx, symbol, startDate, endDate, period)
+}
 
-	var granularity int // seconds
+// QuoteFromCoinbase - Coinbase Pro historical prices for a symbol
+func (c *Client) QuoteFromCoinbase(ctx context.Context, symbol, startDate, endDate string, period Period) (Quote, error) {
 
-	switch period {
-	case Min1:
-		granularity = 60
-	case Min5:
-		granularity = 5 * 60
-	case Min15:
-		granularity = 15 * 60
-	case Min30:
-		granularity = 30 * 60
-	case Min60:
-		granularity = 60 * 60
-	case Daily:
-		granularity = 24 * 60 * 60
-	case Weekly:
-		granularity = 7 * 24 * 60 * 60
-	default:
-		granularity = 24 * 60 * 60
+	start, end, err := parseDates(startDate, endDate)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+
+	return c.coinbase(ctx, symbol, start, end, period)
+}
+
+func (c *Client) coinbase(ctx context.Context, symbol string, start, end time.Time, period Period) (Quote, error) {
+
+	period, err := checkPeriod("coinbase", symbol, period)
+	if err != nil {
+		return NewQuote("", 0), err
 	}
+	granularity := int(period.Duration() / time.Second)
 
 	var quote Quote
 	quote.Symbol = symbol
@@ -938,104 +1433,153 @@ func NewQuoteFromCoinbase(symbol, startDate, endDate string, period Period) (Quo
 	for startBar.Before(end) {
 
 		url := fmt.Sprintf(
-			"https://api.pro.coinbase.com/products/%s/candles?start=%s&end=%s&granularity=%d",
+			"%s/products/%s/candles?start=%s&end=%s&granularity=%d",
+			c.HTTP.baseURL("coinbase"),
 			symbol,
 			url.QueryEscape(startBar.Format(time.RFC3339)),
 			url.QueryEscape(endBar.Format(time.RFC3339)),
 			granularity)
 
-		client := &http.Client{Timeout: ClientTimeout}
-		req, _ := http.NewRequest("GET", url, nil)
-		resp, err := client.Do(req)
-
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			Log.Printf("coinbase error: %v\n", err)
 			return NewQuote("", 0), err
 		}
-		defer resp.Body.Close()
+		resp, err := c.do(req, "coinbase", symbol)
+
+		if err != nil {
+			c.logError("download failed", "coinbase", symbol, err)
+			return NewQuote("", 0), ctxErr(ctx, err)
+		}
 
-		contents, _ := ioutil.ReadAll(resp.Body)
+		contents, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return NewQuote("", 0), netErr(ctx, "coinbase", symbol, err)
+		}
+		if err = checkStatus(resp, "coinbase", symbol); err != nil {
+			c.logError("download failed", "coinbase", symbol, err)
+			return NewQuote("", 0), err
+		}
 
-		type cb [6]float64
+		// numbers kept as text for Client.Exact
+		type cb [6]json.Number
 		var bars []cb
 		err = json.Unmarshal(contents, &bars)
 		if err != nil {
-			Log.Printf("coinbase error: %v\n", err)
+			c.logError("download failed", "coinbase", symbol, err)
+			return NewQuote("", 0), malformed("coinbase", symbol, err)
 		}
 
 		numrows := len(bars)
 		q := NewQuote(symbol, numrows)
+		if c.Exact {
+			q.Exact = make([]ExactBar, numrows)
+		}
 
 		//Log.Printf("numrows=%d, bars=%v\n", numrows, bars)
 
 		for row := 0; row < numrows; row++ {
 			bar := numrows - 1 - row // reverse the order
-			q.Date[bar] = time.Unix(int64(bars[row][0]), 0)
-			q.Open[bar] = bars[row][1]
-			q.High[bar] = bars[row][2]
-			q.Low[bar] = bars[row][3]
-			q.Close[bar] = bars[row][4]
-			q.Volume[bar] = bars[row][5]
-		}
-		quote.Date = append(quote.Date, q.Date...)
-		quote.Open = append(quote.Open, q.Open...)
-		quote.High = append(quote.High, q.High...)
-		quote.Low = append(quote.Low, q.Low...)
-		quote.Close = append(quote.Close, q.Close...)
-		quote.Volume = append(quote.Volume, q.Volume...)
-
-		time.Sleep(time.Second)
+			t, err := bars[row][0].Int64()
+			if err != nil {
+				return NewQuote("", 0), malformed("coinbase", symbol, err)
+			}
+			fields := make([]string, 5)
+			for i := range fields {
+				fields[i] = bars[row][i+1].String()
+			}
+			f, err := parseFloats(fields...)
+			if err != nil {
+				return NewQuote("", 0), malformed("coinbase", symbol, err)
+			}
+			q.Date[bar] = time.Unix(t, 0).UTC()
+			q.Open[bar] = f[0]
+			q.High[bar] = f[1]
+			q.Low[bar] = f[2]
+			q.Close[bar] = f[3]
+			q.Volume[bar] = f[4]
+			if c.Exact {
+				q.Exact[bar] = ExactBar{Decimal(fields[0]), Decimal(fields[1]), Decimal(fields[2]), Decimal(fields[3]), Decimal(fields[4])}
+			}
+		}
+		quote.append(q)
+
+		// the next page waits on the rate limit in c.do, one request per Delay
 		startBar = endBar.Add(step)
 		endBar = startBar.Add(time.Duration(maxBars) * step)
 
 	}
 
-	return quote, nil
+	// pages can overlap at their boundaries
+	quote, err = quote.Merge(Quote{}, PreferNewer)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "coinbase", symbol)
+	return c.stamp(quote, period), nil
 }
 
 // NewQuotesFromCoinbase - create a list of prices from symbols in file
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromCoinbase(filename, startDate, endDate string, period Period) (Quotes, error) {
+	return NewQuotesFromCoinbaseContext(context.Background(), filename, startDate, endDate, period)
+}
 
-	quotes := Quotes{}
-	inFile, err := os.Open(filename)
-	if err != nil {
-		return quotes, err
-	}
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	scanner.Split(bufio.ScanLines)
+// NewQuotesFromCoinbaseContext - NewQuotesFromCoinbase with a context for cancellation
+func NewQuotesFromCoinbaseContext(ctx context.Context, filename, startDate, endDate string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromCoinbase(ctx, filename, startDate, endDate, period)
+}
 
-	for scanner.Scan() {
-		sym := scanner.Text()
-		quote, err := NewQuoteFromCoinbase(sym, startDate, endDate, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + sym)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// QuotesFromCoinbase - create a list of prices from symbols in file
+func (c *Client) QuotesFromCoinbase(ctx context.Context, filename, startDate, endDate string, period Period) (Quotes, error) {
+
+	symbols, err := symbolsFromFile(filename)
+	if err != nil {
+		return Quotes{}, err
 	}
-	return quotes, nil
+	return c.QuotesFromCoinbaseSyms(ctx, symbols, startDate, endDate, period)
 }
 
 // NewQuotesFromCoinbaseSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromCoinbaseSyms(symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+	return NewQuotesFromCoinbaseSymsContext(context.Background(), symbols, startDate, endDate, period)
+}
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromCoinbase(symbol, startDate, endDate, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + symbol)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromCoinbaseSymsContext - NewQuotesFromCoinbaseSyms with a context for cancellation
+func NewQuotesFromCoinbaseSymsContext(ctx context.Context, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromCoinbaseSyms(ctx, symbols, startDate, endDate, period)
+}
+
+// QuotesFromCoinbaseSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromCoinbaseSyms(ctx context.Context, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+
+	results, err := c.downloadEach(ctx, "coinbase", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromCoinbase(ctx, symbol, startDate, endDate, period)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
 }
 
 // NewQuoteFromBittrex - Biitrex historical prices for a symbol
 func NewQuoteFromBittrex(symbol string, period Period) (Quote, error) {
+	return NewQuoteFromBittrexContext(context.Background(), symbol, period)
+}
+
+// NewQuoteFromBittrexContext - NewQuoteFromBittrex with a context for cancellation
+func NewQuoteFromBittrexContext(ctx context.Context, symbol string, period Period) (Quote, error) {
+	return defaultClient().QuoteFromBittrex(ctx, symbol, period)
+}
+
+// QuoteFromBittrex - Biitrex historical prices for a symbol
+func (c *Client) QuoteFromBittrex(ctx context.Context, symbol string, period Period) (Quote, error) {
+
+	period, err := checkPeriod("bittrex", symbol, period)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
 
 	var bittrexPeriod string
 
@@ -1051,28 +1595,39 @@ func NewQuoteFromBittrex(symbol string, period Period) (Quote, error) {
 	case Daily:
 		bittrexPeriod = "day"
 	default:
-		bittrexPeriod = "day"
+		return NewQuote("", 0), newError(ErrInvalidPeriod, "bittrex", symbol, fmt.Errorf("'%s' not supported", period))
 	}
 
 	var quote Quote
 	quote.Symbol = symbol
 
 	url := fmt.Sprintf(
-		"https://bittrex.com/Api/v2.0/pub/market/GetTicks?marketName=%s&tickInterval=%s",
+		"%s/Api/v2.0/pub/market/GetTicks?marketName=%s&tickInterval=%s",
+		c.HTTP.baseURL("bittrex"),
 		symbol,
 		bittrexPeriod)
 
-	client := &http.Client{Timeout: ClientTimeout}
-	req, _ := http.NewRequest("GET", url, nil)
-	resp, err := client.Do(req)
-
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		Log.Printf("bittrex error: %v\n", err)
 		return NewQuote("", 0), err
 	}
+	resp, err := c.do(req, "bittrex", symbol)
+
+	if err != nil {
+		c.logError("download failed", "bittrex", symbol, err)
+		return NewQuote("", 0), ctxErr(ctx, err)
+	}
 	defer resp.Body.Close()
 
-	contents, _ := ioutil.ReadAll(resp.Body)
+	if err = checkStatus(resp, "bittrex", symbol); err != nil {
+		c.logError("download failed", "bittrex", symbol, err)
+		return NewQuote("", 0), err
+	}
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return NewQuote("", 0), netErr(ctx, "bittrex", symbol, err)
+	}
 
 	type OHLC struct {
 		O  float64
@@ -1084,7 +1639,7 @@ func NewQuoteFromBittrex(symbol string, period Period) (Quote, error) {
 		BV float64
 	}
 	type Result struct {
-		Success bool   `json:"succes"`
+		Success bool   `json:"success"`
 		Message string `json:"message"`
 		OHLC    []OHLC `json:"result"`
 	}
@@ -1093,14 +1648,25 @@ func NewQuoteFromBittrex(symbol string, period Period) (Quote, error) {
 
 	err = json.Unmarshal(contents, &result)
 	if err != nil {
-		Log.Printf("bittrex error: %v\n", err)
+		c.logError("download failed", "bittrex", symbol, err)
+		return NewQuote("", 0), malformed("bittrex", symbol, err)
+	}
+	if !result.Success {
+		c.logError("download failed", "bittrex", symbol, errors.New(result.Message))
+		if result.Message == "INVALID_MARKET" {
+			return NewQuote("", 0), newError(ErrSymbolNotFound, "bittrex", symbol, nil)
+		}
+		return NewQuote("", 0), malformed("bittrex", symbol, errors.New(result.Message))
 	}
 
 	numrows := len(result.OHLC)
 	q := NewQuote(symbol, numrows)
 
 	for bar := 0; bar < numrows; bar++ {
-		q.Date[bar], _ = time.Parse("2006-01-02T15:04:05", result.OHLC[bar].T) //"2017-11-28T16:50:00"
+		q.Date[bar], err = time.Parse("2006-01-02T15:04:05", result.OHLC[bar].T) //"2017-11-28T16:50:00"
+		if err != nil {
+			return NewQuote("", 0), malformed("bittrex", symbol, err)
+		}
 		q.Open[bar] = result.OHLC[bar].O
 		q.High[bar] = result.OHLC[bar].H
 		q.Low[bar] = result.OHLC[bar].L
@@ -1114,55 +1680,81 @@ func NewQuoteFromBittrex(symbol string, period Period) (Quote, error) {
 	quote.Close = append(quote.Close, q.Close...)
 	quote.Volume = append(quote.Volume, q.Volume...)
 
-	return quote, nil
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "bittrex", symbol)
+	return c.stamp(quote, period), nil
 }
 
 // NewQuotesFromBittrex - create a list of prices from symbols in file
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromBittrex(filename string, period Period) (Quotes, error) {
+	return NewQuotesFromBittrexContext(context.Background(), filename, period)
+}
 
-	quotes := Quotes{}
-	inFile, err := os.Open(filename)
-	if err != nil {
-		return quotes, err
-	}
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	scanner.Split(bufio.ScanLines)
+// NewQuotesFromBittrexContext - NewQuotesFromBittrex with a context for cancellation
+func NewQuotesFromBittrexContext(ctx context.Context, filename string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromBittrex(ctx, filename, period)
+}
 
-	for scanner.Scan() {
-		sym := scanner.Text()
-		quote, err := NewQuoteFromBittrex(sym, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + sym)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// QuotesFromBittrex - create a list of prices from symbols in file
+func (c *Client) QuotesFromBittrex(ctx context.Context, filename string, period Period) (Quotes, error) {
+
+	symbols, err := symbolsFromFile(filename)
+	if err != nil {
+		return Quotes{}, err
 	}
-	return quotes, nil
+	return c.QuotesFromBittrexSyms(ctx, symbols, period)
 }
 
 // NewQuotesFromBittrexSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromBittrexSyms(symbols []string, period Period) (Quotes, error) {
+	return NewQuotesFromBittrexSymsContext(context.Background(), symbols, period)
+}
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromBittrex(symbol, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + symbol)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromBittrexSymsContext - NewQuotesFromBittrexSyms with a context for cancellation
+func NewQuotesFromBittrexSymsContext(ctx context.Context, symbols []string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromBittrexSyms(ctx, symbols, period)
+}
+
+// QuotesFromBittrexSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromBittrexSyms(ctx context.Context, symbols []string, period Period) (Quotes, error) {
+
+	results, err := c.downloadEach(ctx, "bittrex", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromBittrex(ctx, symbol, period)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
 }
 
 // NewQuoteFromBinance - Binance historical prices for a symbol
 func NewQuoteFromBinance(symbol string, startDate, endDate string, period Period) (Quote, error) {
+	return NewQuoteFromBinanceContext(context.Background(), symbol, startDate, endDate, period)
+}
+
+// NewQuoteFromBinanceContext - NewQuoteFromBinance with a context for cancellation
+func NewQuoteFromBinanceContext(ctx context.Context, symbol string, startDate, endDate string, period Period) (Quote, error) {
+	return defaultClient().QuoteFromBinance(ctx, symbol, startDate, endDate, period)
+}
+
+// QuoteFromBinance - Binance historical prices for a symbol
+func (c *Client) QuoteFromBinance(ctx context.Context, symbol string, startDate, endDate string, period Period) (Quote, error) {
+
+	start, end, err := parseDates(startDate, endDate)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+
+	return c.binance(ctx, symbol, start, end, period)
+}
 
-	start := ParseDateString(startDate)
-	end := ParseDateString(endDate)
+func (c *Client) binance(ctx context.Context, symbol string, start, end time.Time, period Period) (Quote, error) {
+
+	period, err := checkPeriod("binance", symbol, period)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
 
 	var interval string
 	var granularity int // seconds
@@ -1192,6 +1784,9 @@ func NewQuoteFromBinance(symbol string, startDate, endDate string, period Period
 	case Hour4:
 		interval = "4h"
 		granularity = 4 * 60 * 60
+	case Hour6:
+		interval = "6h"
+		granularity = 6 * 60 * 60
 	case Hour8:
 		interval = "8h"
 		granularity = 8 * 60 * 60
@@ -1211,8 +1806,7 @@ func NewQuoteFromBinance(symbol string, startDate, endDate string, period Period
 		interval = "1M"
 		granularity = 30 * 24 * 60 * 60
 	default:
-		interval = "1d"
-		granularity = 24 * 60 * 60
+		return NewQuote("", 0), newError(ErrInvalidPeriod, "binance", symbol, fmt.Errorf("'%s' not supported", period))
 	}
 
 	var quote Quote
@@ -1232,33 +1826,59 @@ func NewQuoteFromBinance(symbol string, startDate, endDate string, period Period
 	for startBar.Before(end) {
 
 		url := fmt.Sprintf(
-			"https://api.binance.com/api/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d",
+			"%s/api/v1/klines?symbol=%s&interval=%s&startTime=%d&endTime=%d",
+			c.HTTP.baseURL("binance"),
 			strings.ToUpper(symbol),
 			interval,
 			startBar.UnixNano()/1000000,
 			endBar.UnixNano()/1000000)
 		//log.Println(url)
-		client := &http.Client{Timeout: ClientTimeout}
-		req, _ := http.NewRequest("GET", url, nil)
-		resp, err := client.Do(req)
-
+		req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 		if err != nil {
-			Log.Printf("binance error: %v\n", err)
 			return NewQuote("", 0), err
 		}
-		defer resp.Body.Close()
+		resp, err := c.do(req, "binance", symbol)
 
-		contents, _ := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			c.logError("download failed", "binance", symbol, err)
+			return NewQuote("", 0), ctxErr(ctx, err)
+		}
+
+		contents, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return NewQuote("", 0), netErr(ctx, "binance", symbol, err)
+		}
+		if err = checkStatus(resp, "binance", symbol); err != nil {
+			c.logError("download failed", "binance", symbol, err)
+			// binance reports unknown symbols as a bad request
+			var apiErr struct {
+				Code int    `json:"code"`
+				Msg  string `json:"msg"`
+			}
+			if json.Unmarshal(contents, &apiErr) == nil && apiErr.Code == -1121 {
+				err = &Error{Kind: ErrSymbolNotFound, Source: "binance", Symbol: symbol, Status: resp.StatusCode, Err: errors.New(apiErr.Msg)}
+			}
+			return NewQuote("", 0), err
+		}
 
 		type binance [12]interface{}
 		var bars []binance
 		err = json.Unmarshal(contents, &bars)
 		if err != nil {
-			Log.Printf("binance error: %v\n", err)
+			c.logError("download failed", "binance", symbol, err)
+			return NewQuote("", 0), malformed("binance", symbol, err)
 		}
 
 		numrows := len(bars)
 		q := NewQuote(symbol, numrows)
+		q.QuoteVolume = make([]float64, numrows)
+		q.Trades = make([]float64, numrows)
+		q.TakerBuyVolume = make([]float64, numrows)
+		q.TakerBuyQuoteVolume = make([]float64, numrows)
+		if c.Exact {
+			q.Exact = make([]ExactBar, numrows)
+		}
 		//fmt.Printf("numrows=%d, bars=%v\n", numrows, bars)
 
 		/*
@@ -1277,76 +1897,117 @@ func NewQuoteFromBinance(symbol string, startDate, endDate string, period Period
 		*/
 
 		for bar := 0; bar < numrows; bar++ {
-			q.Date[bar] = time.Unix(int64(bars[bar][6].(float64))/1000, 0)
-			q.Open[bar], _ = strconv.ParseFloat(bars[bar][1].(string), 64)
-			q.High[bar], _ = strconv.ParseFloat(bars[bar][2].(string), 64)
-			q.Low[bar], _ = strconv.ParseFloat(bars[bar][3].(string), 64)
-			q.Close[bar], _ = strconv.ParseFloat(bars[bar][4].(string), 64)
-			q.Volume[bar], _ = strconv.ParseFloat(bars[bar][5].(string), 64)
-		}
-		quote.Date = append(quote.Date, q.Date...)
-		quote.Open = append(quote.Open, q.Open...)
-		quote.High = append(quote.High, q.High...)
-		quote.Low = append(quote.Low, q.Low...)
-		quote.Close = append(quote.Close, q.Close...)
-		quote.Volume = append(quote.Volume, q.Volume...)
-
-		time.Sleep(time.Second)
+			openTime, ok := bars[bar][0].(float64)
+			if !ok {
+				return NewQuote("", 0), malformed("binance", symbol, fmt.Errorf("bad open time %v", bars[bar][0]))
+			}
+			trades, ok := bars[bar][8].(float64)
+			if !ok {
+				return NewQuote("", 0), malformed("binance", symbol, fmt.Errorf("bad trade count %v", bars[bar][8]))
+			}
+			var fields [8]string
+			for i, col := range []int{1, 2, 3, 4, 5, 7, 9, 10} {
+				if fields[i], ok = bars[bar][col].(string); !ok {
+					return NewQuote("", 0), malformed("binance", symbol, fmt.Errorf("bad price %v", bars[bar][col]))
+				}
+			}
+			f, err := parseFloats(fields[:]...)
+			if err != nil {
+				return NewQuote("", 0), malformed("binance", symbol, err)
+			}
+			q.Date[bar] = time.Unix(int64(openTime)/1000, 0).UTC()
+			q.Open[bar] = f[0]
+			q.High[bar] = f[1]
+			q.Low[bar] = f[2]
+			q.Close[bar] = f[3]
+			q.Volume[bar] = f[4]
+			q.QuoteVolume[bar] = f[5]
+			q.Trades[bar] = trades
+			q.TakerBuyVolume[bar] = f[6]
+			q.TakerBuyQuoteVolume[bar] = f[7]
+			if c.Exact {
+				q.Exact[bar] = ExactBar{Decimal(fields[0]), Decimal(fields[1]), Decimal(fields[2]), Decimal(fields[3]), Decimal(fields[4])}
+			}
+		}
+		quote.append(q)
+
+		// the next page waits on the rate limit in c.do, one request per Delay
 		startBar = endBar.Add(step)
 		endBar = startBar.Add(time.Duration(maxBars) * step)
 
 	}
-	return quote, nil
+
+	// pages can overlap at their boundaries
+	quote, err = quote.Merge(Quote{}, PreferNewer)
+	if err != nil {
+		return NewQuote("", 0), err
+	}
+	quote.Precision, quote.VolumePrecision = c.precision(ctx, "binance", symbol)
+	return c.stamp(quote, period), nil
 }
 
 // NewQuotesFromBinance - create a list of prices from symbols in file
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromBinance(filename string, startDate, endDate string, period Period) (Quotes, error) {
-	quotes := Quotes{}
-	inFile, err := os.Open(filename)
-	if err != nil {
-		return quotes, err
-	}
-	defer inFile.Close()
-	scanner := bufio.NewScanner(inFile)
-	scanner.Split(bufio.ScanLines)
+	return NewQuotesFromBinanceContext(context.Background(), filename, startDate, endDate, period)
+}
 
-	for scanner.Scan() {
-		sym := scanner.Text()
-		quote, err := NewQuoteFromBinance(sym, startDate, endDate, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + sym)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromBinanceContext - NewQuotesFromBinance with a context for cancellation
+func NewQuotesFromBinanceContext(ctx context.Context, filename string, startDate, endDate string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromBinance(ctx, filename, startDate, endDate, period)
+}
+
+// QuotesFromBinance - create a list of prices from symbols in file
+func (c *Client) QuotesFromBinance(ctx context.Context, filename string, startDate, endDate string, period Period) (Quotes, error) {
+
+	symbols, err := symbolsFromFile(filename)
+	if err != nil {
+		return Quotes{}, err
 	}
-	return quotes, nil
+	return c.QuotesFromBinanceSyms(ctx, symbols, startDate, endDate, period)
 }
 
 // NewQuotesFromBinanceSyms - create a list of prices from symbols in string array
+// symbols that fail to download are left out and reported in a *DownloadError
 func NewQuotesFromBinanceSyms(symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+	return NewQuotesFromBinanceSymsContext(context.Background(), symbols, startDate, endDate, period)
+}
 
-	quotes := Quotes{}
-	for _, symbol := range symbols {
-		quote, err := NewQuoteFromBinance(symbol, startDate, endDate, period)
-		if err == nil {
-			quotes = append(quotes, quote)
-		} else {
-			Log.Println("error downloading " + symbol)
-		}
-		time.Sleep(Delay * time.Millisecond)
+// NewQuotesFromBinanceSymsContext - NewQuotesFromBinanceSyms with a context for cancellation
+func NewQuotesFromBinanceSymsContext(ctx context.Context, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+	return defaultClient().QuotesFromBinanceSyms(ctx, symbols, startDate, endDate, period)
+}
+
+// QuotesFromBinanceSyms - create a list of prices from symbols in string array
+func (c *Client) QuotesFromBinanceSyms(ctx context.Context, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+
+	results, err := c.downloadEach(ctx, "binance", symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		return c.QuoteFromBinance(ctx, symbol, startDate, endDate, period)
+	})
+	if err != nil {
+		return results.Quotes(), err
 	}
-	return quotes, nil
+	return results.Quotes(), results.Err()
 }
 
 // NewEtfList - download a list of etf symbols to an array of strings
 func NewEtfList() ([]string, error) {
+	return NewEtfListContext(context.Background())
+}
+
+// NewEtfListContext - NewEtfList with a context for cancellation
+func NewEtfListContext(ctx context.Context) ([]string, error) {
+	return defaultClient().EtfList(ctx)
+}
+
+// EtfList - download a list of etf symbols to an array of strings
+func (c *Client) EtfList(ctx context.Context) ([]string, error) {
 
 	var symbols []string
 
-	buf, err := getAnonFTP("ftp.nasdaqtrader.com", "21", "symboldirectory", "otherlisted.txt")
+	buf, err := getAnonFTP(ctx, c.HTTP.baseURL("nasdaq-ftp"), "symboldirectory", "otherlisted.txt")
 	if err != nil {
-		Log.Println(err)
+		c.log(LevelError, "market list failed", F("market", "etf"), F("error", err))
 		return symbols, err
 	}
 
@@ -1363,10 +2024,20 @@ func NewEtfList() ([]string, error) {
 
 // NewEtfFile - download a list of etf symbols to a file
 func NewEtfFile(filename string) error {
+	return NewEtfFileContext(context.Background(), filename)
+}
+
+// NewEtfFileContext - NewEtfFile with a context for cancellation
+func NewEtfFileContext(ctx context.Context, filename string) error {
+	return defaultClient().EtfFile(ctx, filename)
+}
+
+// EtfFile - download a list of etf symbols to a file
+func (c *Client) EtfFile(ctx context.Context, filename string) error {
 	if filename == "" {
 		filename = "etf.txt"
 	}
-	etfs, err := NewEtfList()
+	etfs, err := c.EtfList(ctx)
 	if err != nil {
 		return err
 	}
@@ -1412,12 +2083,6 @@ var ValidMarkets = [...]string{"etf",
 
 // ValidMarket - validate market string
 func ValidMarket(market string) bool {
-	if strings.HasPrefix(market, "tiingo") {
-		if os.Getenv("TIINGO_API_TOKEN") == "" {
-			fmt.Println("ERROR: Requires TIINGO_API_TOKEN to be set")
-			return false
-		}
-	}
 	for _, v := range ValidMarkets {
 		if v == market {
 			return true
@@ -1428,11 +2093,26 @@ func ValidMarket(market string) bool {
 
 // NewMarketList - download a list of market symbols to an array of strings
 func NewMarketList(market string) ([]string, error) {
+	return NewMarketListContext(context.Background(), market)
+}
+
+// NewMarketListContext - NewMarketList with a context for cancellation
+func NewMarketListContext(ctx context.Context, market string) ([]string, error) {
+	return defaultClient().MarketList(ctx, market)
+}
+
+// MarketList - download a list of market symbols to an array of strings
+func (c *Client) MarketList(ctx context.Context, market string) ([]string, error) {
 
 	var symbols []string
 	if !ValidMarket(market) {
 		return symbols, fmt.Errorf("invalid market")
 	}
+	// restore with the tiingo markets
+	//if strings.HasPrefix(market, "tiingo") && c.TiingoToken == "" {
+	//	c.log(LevelError, "tiingo markets require an api token", F("market", market))
+	//	return symbols, newError(ErrAuthFailed, "tiingo", market, errors.New("missing api token"))
+	//}
 	var url string
 	switch market {
 	// case "nasdaq":
@@ -1478,19 +2158,19 @@ func NewMarketList(market string) ([]string, error) {
 	// case "transportation":
 	// 	url = "http://old.nasdaq.com/screening/companies-by-industry.aspx?industry=Transportation&render=download"
 	case "bittrex-btc":
-		url = "https://bittrex.com/Api/v2.0/pub/markets/getmarketsummaries"
+		url = c.HTTP.baseURL("bittrex") + "/Api/v2.0/pub/markets/getmarketsummaries"
 	case "bittrex-eth":
-		url = "https://bittrex.com/Api/v2.0/pub/markets/getmarketsummaries"
+		url = c.HTTP.baseURL("bittrex") + "/Api/v2.0/pub/markets/getmarketsummaries"
 	case "bittrex-usdt":
-		url = "https://bittrex.com/Api/v2.0/pub/markets/getmarketsummaries"
+		url = c.HTTP.baseURL("bittrex") + "/Api/v2.0/pub/markets/getmarketsummaries"
 	case "binance-bnb":
-		url = "https://api.binance.com/api/v1/exchangeInfo"
+		url = c.HTTP.baseURL("binance") + "/api/v1/exchangeInfo"
 	case "binance-btc":
-		url = "https://api.binance.com/api/v1/exchangeInfo"
+		url = c.HTTP.baseURL("binance") + "/api/v1/exchangeInfo"
 	case "binance-eth":
-		url = "https://api.binance.com/api/v1/exchangeInfo"
+		url = c.HTTP.baseURL("binance") + "/api/v1/exchangeInfo"
 	case "binance-usdt":
-		url = "https://api.binance.com/api/v1/exchangeInfo"
+		url = c.HTTP.baseURL("binance") + "/api/v1/exchangeInfo"
 	//case "tiingo-btc":
 	//	url = fmt.Sprintf("https://api.tiingo.com/tiingo/crypto?token=%s", os.Getenv("TIINGO_API_TOKEN"))
 	//case "tiingo-eth":
@@ -1498,20 +2178,26 @@ func NewMarketList(market string) ([]string, error) {
 	//case "tiingo-usd":
 	//	url = fmt.Sprintf("https://api.tiingo.com/tiingo/crypto?token=%s", os.Getenv("TIINGO_API_TOKEN"))
 	case "coinbase":
-		url = "https://api.pro.coinbase.com/products"
+		url = c.HTTP.baseURL("coinbase") + "/products"
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return symbols, err
+	}
 	req.Header.Add("User-Agent", "markcheno/go-quote")
 	req.Header.Add("Accept", "application/xml")
 	req.Header.Add("Content-Type", "application/xml; charset=utf-8")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := c.do(req, strings.Split(market, "-")[0], market)
 	if err != nil {
-		return symbols, err
+		return symbols, ctxErr(ctx, err)
 	}
 	defer resp.Body.Close()
 
+	if err = checkStatus(resp, strings.Split(market, "-")[0], market); err != nil {
+		return symbols, err
+	}
+
 	if strings.HasPrefix(market, "bittrex") {
 		buf := new(bytes.Buffer)
 		buf.ReadFrom(resp.Body)
@@ -1576,7 +2262,7 @@ func getBinanceMarket(market, rawdata string) ([]string, error) {
 	var markets Markets
 	err := json.Unmarshal([]byte(rawdata), &markets)
 	if err != nil {
-		fmt.Println(err)
+		return nil, malformed("binance", market, err)
 	}
 
 	var symbols []string
@@ -1592,7 +2278,7 @@ func getBinanceMarket(market, rawdata string) ([]string, error) {
 		}
 	}
 
-	return symbols, err
+	return symbols, nil
 }
 
 // func getTiingoCryptoMarket(market, rawdata string) ([]string, error) {
@@ -1673,7 +2359,7 @@ func getBittrexMarket(market, rawdata string) ([]string, error) {
 	var markets Markets
 	err := json.Unmarshal([]byte(rawdata), &markets)
 	if err != nil {
-		fmt.Println(err)
+		return nil, malformed("bittrex", market, err)
 	}
 	var symbols []string
 	for _, mkt := range markets.Result {
@@ -1686,7 +2372,7 @@ func getBittrexMarket(market, rawdata string) ([]string, error) {
 		}
 	}
 
-	return symbols, err
+	return symbols, nil
 }
 
 func getCoinbaseMarket(market, rawdata string) ([]string, error) {
@@ -1715,7 +2401,7 @@ func getCoinbaseMarket(market, rawdata string) ([]string, error) {
 
 	err := json.Unmarshal([]byte(rawdata), &markets)
 	if err != nil {
-		fmt.Println(err)
+		return nil, malformed("coinbase", market, err)
 	}
 
 	var symbols []string
@@ -1725,20 +2411,40 @@ func getCoinbaseMarket(market, rawdata string) ([]string, error) {
 
 	sort.Strings(symbols)
 
-	return symbols, err
+	return symbols, nil
 }
 
 // NewMarketFile - download a list of market symbols to a file
 func NewMarketFile(market, filename string) error {
+	return NewMarketFileContext(context.Background(), market, filename)
+}
+
+// NewMarketFileContext - NewMarketFile with a context for cancellation
+func NewMarketFileContext(ctx context.Context, market, filename string) error {
+	return defaultClient().MarketFile(ctx, market, filename)
+}
+
+// MarketFile - download a list of market symbols to a file
+func (c *Client) MarketFile(ctx context.Context, market, filename string) error {
 	if market == "allmarkets" {
+		// write every market that downloads, then report the failures
+		var failed DownloadResults
 		for _, m := range ValidMarkets {
 			filename = m + ".txt"
-			syms, err := NewMarketList(m)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			syms, err := c.MarketList(ctx, m)
+			if err == nil {
+				err = ioutil.WriteFile(filename, []byte(strings.Join(syms, "\n")), 0644)
+			}
 			if err != nil {
-				Log.Println(err)
+				c.log(LevelError, "market list failed", F("market", m), F("error", err))
+				failed = append(failed, DownloadResult{Symbol: m, Err: err})
 			}
-			ba := []byte(strings.Join(syms, "\n"))
-			ioutil.WriteFile(filename, ba, 0644)
+		}
+		if len(failed) > 0 {
+			return &DownloadError{Failed: failed, Total: len(ValidMarkets)}
 		}
 		return nil
 	}
@@ -1750,7 +2456,7 @@ func NewMarketFile(market, filename string) error {
 	if filename == "" {
 		filename = market + ".txt"
 	}
-	syms, err := NewMarketList(market)
+	syms, err := c.MarketList(ctx, market)
 	if err != nil {
 		return err
 	}
@@ -1781,54 +2487,148 @@ func deleteEmpty(s []string) []string {
 	return r
 }
 
-// Grab a file via anonymous FTP
-func getAnonFTP(addr, port string, dir string, fname string) ([]byte, error) {
+// sleepContext - pause for d, returning early with ctx.Err() if ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// ctxErr - report the context error in place of err once ctx is done
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// watchConn - unblock pending i/o on c once ctx is done, until the
+// returned stop function is called
+func watchConn(ctx context.Context, c net.Conn) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// Grab a file via anonymous FTP. Failures are *Errors of kind ErrNetwork,
+// ErrBadStatus for a refused command or ErrMalformedResponse for a reply
+// that can't be understood.
+func getAnonFTP(ctx context.Context, addr string, dir string, fname string) ([]byte, error) {
 
-	var err error
-	var contents []byte
 	const timeout = 5 * time.Second
+	fail := func(err error) ([]byte, error) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if perr, ok := err.(*textproto.Error); ok {
+			return nil, &Error{Kind: ErrBadStatus, Source: "nasdaq-ftp", Symbol: fname, Status: perr.Code, Err: err}
+		}
+		return nil, newError(ErrNetwork, "nasdaq-ftp", fname, err)
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
 
-	nconn, err := net.DialTimeout("tcp", addr+":"+port, timeout)
+	nconn, err := dialer.DialContext(ctx, "tcp", addr)
 	if err != nil {
-		return contents, err
+		return fail(err)
 	}
 	defer nconn.Close()
 
+	defer watchConn(ctx, nconn)()
+
 	conn := textproto.NewConn(nconn)
-	_, _, _ = conn.ReadResponse(2)
 	defer conn.Close()
+	if _, _, err = conn.ReadResponse(2); err != nil {
+		return fail(err)
+	}
 
-	_ = conn.PrintfLine("USER anonymous")
-	_, _, _ = conn.ReadResponse(0)
-
-	_ = conn.PrintfLine("PASS anonymous")
-	_, _, _ = conn.ReadResponse(230)
-
-	_ = conn.PrintfLine("CWD %s", dir)
-	_, _, _ = conn.ReadResponse(250)
+	// send a command and read its reply, whose code must start with expect
+	cmd := func(expect int, format string, args ...interface{}) (string, error) {
+		if err := conn.PrintfLine(format, args...); err != nil {
+			return "", err
+		}
+		_, message, err := conn.ReadResponse(expect)
+		return message, err
+	}
+	if _, err = cmd(0, "USER anonymous"); err != nil {
+		return fail(err)
+	}
+	if _, err = cmd(2, "PASS anonymous"); err != nil {
+		return fail(err)
+	}
+	if _, err = cmd(250, "CWD %s", dir); err != nil {
+		return fail(err)
+	}
 
-	_ = conn.PrintfLine("PASV")
-	_, message, _ := conn.ReadResponse(1)
+	message, err := cmd(227, "PASV")
+	if err != nil {
+		return fail(err)
+	}
+	dport, err := pasvPort(message)
+	if err != nil {
+		return nil, malformed("nasdaq-ftp", fname, err)
+	}
 
-	// PASV response format : 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2).
-	start, end := strings.Index(message, "("), strings.Index(message, ")")
-	s := strings.Split(message[start:end], ",")
-	l1, _ := strconv.Atoi(s[len(s)-2])
-	l2, _ := strconv.Atoi(s[len(s)-1])
-	dport := l1*256 + l2
-
-	_ = conn.PrintfLine("RETR %s", fname)
-	_, _, err = conn.ReadResponse(1)
-	dconn, err := net.DialTimeout("tcp", addr+":"+strconv.Itoa(dport), timeout)
+	if _, err = cmd(1, "RETR %s", fname); err != nil {
+		return fail(err)
+	}
+	dconn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(dport)))
+	if err != nil {
+		return fail(err)
+	}
 	defer dconn.Close()
+	defer watchConn(ctx, dconn)()
 
-	contents, err = ioutil.ReadAll(dconn)
+	contents, err := ioutil.ReadAll(dconn)
 	if err != nil {
-		return contents, err
+		return fail(err)
+	}
+	if err = dconn.Close(); err != nil {
+		return fail(err)
+	}
+	if _, _, err = conn.ReadResponse(2); err != nil {
+		return fail(err)
 	}
 
-	_ = dconn.Close()
-	_, _, _ = conn.ReadResponse(2)
+	return contents, ctx.Err()
+}
 
-	return contents, nil
+// pasvPort - data port of a PASV reply, whose format is
+// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2).
+func pasvPort(message string) (int, error) {
+	start, end := strings.Index(message, "("), strings.Index(message, ")")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("ftp: unexpected PASV reply '%s'", message)
+	}
+	fields := strings.Split(message[start+1:end], ",")
+	if len(fields) != 6 {
+		return 0, fmt.Errorf("ftp: unexpected PASV reply '%s'", message)
+	}
+	var b [6]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 0 || n > 255 {
+			return 0, fmt.Errorf("ftp: unexpected PASV reply '%s'", message)
+		}
+		b[i] = n
+	}
+	return b[4]*256 + b[5], nil
 }
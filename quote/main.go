@@ -13,11 +13,17 @@ Licensed under terms of MIT license
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"log"
 	"os"
+	"os/signal"
+	"strings"
 	"time"
+	_ "time/tzdata" // zone names work without a system database
 
 	"github.com/markcheno/go-quote"
 )
@@ -32,20 +38,43 @@ Options:
   -h -help             show help
   -v -version          show version
   -years=<years>       number of years to download [default=5]
-  -start=<datestr>     yyyy[-[mm-[dd]]]
-  -end=<datestr>       yyyy[-[mm-[dd]]] [default=today]
+  -start=<datestr>     start date, see Dates below
+  -end=<datestr>       end date, see Dates below [default=now]
   -infile=<filename>   list of symbols to download
   -outfile=<filename>  output filename
   -period=<period>     1m|3m|5m|15m|30m|1h|2h|4h|6h|8h|12h|d|3d|w|m [default=d]
+                       resampled from shorter bars when the source lacks it
   -source=<source>     yahoo|tiingo|tiingo-crypto|coinbase|bittrex|binance [default=yahoo]
   -token=<tiingo_tok>  tingo api token [default=TIINGO_API_TOKEN]
-  -format=<format>     (csv|json|hs|ami) [default=csv]
-  -adjust=<bool>       adjust yahoo prices [default=true]
+  -format=<format>     (csv|json|hs|ami|wide) [default=csv]
+  -field=<field>       column of -format=wide, one per symbol [default=close]
+                       open|high|low|close|volume|adjclose|...
+  -tz=<zone>           utc|local|exchange|<zone name> for output dates [default=utc]
+  -bartime=<time>      stamp bars with their open|close time [default=open]
+  -precision=<digits>  decimal places of prices [default=source tick size]
+  -exact=<bool>        write prices exactly as the exchange sent them (binance|coinbase) [default=false]
+  -events=<events>     download div,split or all events instead of prices (yahoo|tiingo)
+  -adjust=<bool>       write adjusted yahoo and tiingo prices as open..close in hs|ami [default=true]
   -all=<bool>          all in one file (true|false) [default=false]
   -log=<dest>          filename|stdout|stderr|discard [default=stdout]
+  -loglevel=<level>    debug|info|warn|error [default=info]
+  -logformat=<format>  text|json [default=text]
   -delay=<ms>          delay in milliseconds between quote requests
-
-Note: not all periods work with all sources
+  -workers=<n>         number of concurrent downloads [default=1]
+  -rate=<n>            max requests per second per source [default=1000/delay]
+  -retries=<n>         retries after a transient failure [default=3]
+  -backoff=<ms>        initial delay in milliseconds between retries [default=500]
+
+Note: not all periods work with all sources, the seconds 60, 300, 900, 1800
+and 3600 are still accepted for 1m, 5m, 15m, 30m and 1h
+
+Dates:
+  yyyy[-mm[-dd]]       start of the year, month or day in UTC
+  yyyy-mm-dd hh:mm     time of day in UTC, seconds and a zone are optional
+  rfc3339              e.g. 2021-03-15T09:30:00-05:00
+  <epoch>              unix seconds or milliseconds
+  now|today|yesterday|ytd|mtd
+  -<n><unit>           offset from now, unit s|m|h|d|w|mo|y e.g. -30d, -6h
 
 Valid markets:
 etfs:       etf
@@ -54,26 +83,35 @@ crypto:     bittrex-btc,bittrex-eth,bittrex-usdt,
             coinbase
 `
 
-const (
-	version    = "0.2"
-	dateFormat = "2006-01-02"
-)
+const version = "0.2"
 
 type quoteflags struct {
-	years   int
-	delay   int
-	start   string
-	end     string
-	period  string
-	source  string
-	token   string
-	infile  string
-	outfile string
-	format  string
-	log     string
-	all     bool
-	adjust  bool
-	version bool
+	years     int
+	delay     int
+	workers   int
+	rate      float64
+	retries   int
+	backoff   int
+	start     string
+	end       string
+	period    string
+	source    string
+	token     string
+	infile    string
+	outfile   string
+	format    string
+	field     string
+	log       string
+	logLevel  string
+	logFormat string
+	tz        string
+	barTime   string
+	precision int
+	events    string
+	all       bool
+	adjust    bool
+	exact     bool
+	version   bool
 }
 
 func check(e error) {
@@ -88,92 +126,106 @@ func check(e error) {
 func checkFlags(flags quoteflags) error {
 
 	// validate source
-	if flags.source != "yahoo" &&
-		flags.source != "tiingo" &&
-		flags.source != "tiingo-crypto" &&
-		flags.source != "coinbase" &&
-		flags.source != "bittrex" &&
-		flags.source != "binance" {
-		return fmt.Errorf("invalid source, must be either 'yahoo', 'tiingo', 'coinbase', 'bittrex', or 'binance'")
+	src, err := quote.NewSource(flags.source, quote.SourceOptions{})
+	if err != nil {
+		return err
 	}
 
-	// validate period
-	if flags.source == "yahoo" &&
-		(flags.period == "1m" || flags.period == "5m" || flags.period == "15m" || flags.period == "30m" || flags.period == "1h") {
-		return fmt.Errorf("invalid period for yahoo, must be 'd'")
-	}
-	if flags.source == "tiingo" {
-		// check period
-		if flags.period != "d" {
-			return fmt.Errorf("invalid period for tiingo, must be 'd'")
+	// validate dates
+	if _, _, err := getTimes(flags); err != nil {
+		return err
+	}
+
+	// validate events
+	if flags.events != "" {
+		if _, err := quote.ParseEvents(flags.events); err != nil {
+			return err
 		}
-		// check token
-		if flags.token == "" {
-			return fmt.Errorf("missing token for tiingo, must be passed or TIINGO_API_TOKEN must be set")
+		if !quote.ActionsSupported(flags.source) {
+			return fmt.Errorf("events not supported by %s, use yahoo or tiingo", flags.source)
 		}
+		if flags.format != "csv" && flags.format != "json" {
+			return fmt.Errorf("events can only be written as csv or json")
+		}
+	}
+
+	// validate wide field
+	if flags.format == "wide" {
+		if flags.events != "" {
+			return fmt.Errorf("events can only be written as csv or json")
+		}
+		if !validField(flags.field) {
+			return fmt.Errorf("invalid field '%s', must be one of %s", flags.field, strings.Join(quote.Fields(), "|"))
+		}
+	}
+
+	// validate precision
+	if flags.precision < 0 || flags.precision > 16 {
+		return fmt.Errorf("invalid precision %d, must be 0 to 16", flags.precision)
 	}
 
-	if flags.source == "tiingo-crypto" &&
-		!(flags.period == "1m" ||
-			flags.period == "3m" ||
-			flags.period == "5m" ||
-			flags.period == "15m" ||
-			flags.period == "30m" ||
-			flags.period == "1h" ||
-			flags.period == "2h" ||
-			flags.period == "4h" ||
-			flags.period == "6h" ||
-			flags.period == "8h" ||
-			flags.period == "12h" ||
-			flags.period == "d") {
-		return fmt.Errorf("invalid source for tiingo-crypto, must be '1m', '3m', '5m', '15m', '30m', '1h', '2h', '4h', '6h', '8h', '12h', '1d', '3d', '1w', or '1M'")
-	}
-
-	if flags.source == "tiingo-crypto" && flags.token == "" {
-		return fmt.Errorf("missing token for tiingo-crypto, must be passed or TIINGO_API_TOKEN must be set")
-	}
-
-	if flags.source == "bittrex" && !(flags.period == "1m" || flags.period == "5m" || flags.period == "30m" || flags.period == "1h" || flags.period == "d") {
-		return fmt.Errorf("invalid source for bittrex, must be '1m', '5m', '30m', '1h' or 'd'")
-	}
-
-	if flags.source == "binance" &&
-		!(flags.period == "1m" ||
-			flags.period == "3m" ||
-			flags.period == "5m" ||
-			flags.period == "15m" ||
-			flags.period == "30m" ||
-			flags.period == "1h" ||
-			flags.period == "2h" ||
-			flags.period == "4h" ||
-			flags.period == "6h" ||
-			flags.period == "8h" ||
-			flags.period == "12h" ||
-			flags.period == "d" ||
-			flags.period == "3d" ||
-			flags.period == "w" ||
-			flags.period == "m") {
-		return fmt.Errorf("invalid source for binance, must be '1m', '3m', '5m', '15m', '30m', '1h', '2h', '4h', '6h', '8h', '12h', '1d', '3d', '1w', or '1M'")
+	// validate time zone
+	if _, err := getLocation(flags); err != nil {
+		return err
+	}
+
+	// validate period
+	period, err := quote.ParsePeriod(flags.period)
+	if err != nil {
+		return err
+	}
+	if _, err := quote.BasePeriod(src, period); err != nil {
+		return fmt.Errorf("invalid period for %s, must be one of %s or built from them", flags.source, periodList(src.Periods()))
+	}
+
+	// check token
+	if (flags.source == "tiingo" || flags.source == "tiingo-crypto") && flags.token == "" {
+		return fmt.Errorf("missing token for %s, must be passed or TIINGO_API_TOKEN must be set", flags.source)
 	}
 
 	return nil
 }
 
-func setOutput(flags quoteflags) error {
+// validField - report whether field is a column -format=wide can write
+func validField(field string) bool {
+	for _, f := range quote.Fields() {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func setOutput(c *quote.Client, flags quoteflags) error {
 	var err error
+	var w io.Writer
 	if flags.log == "stdout" {
-		quote.Log.SetOutput(os.Stdout)
+		w = os.Stdout
 	} else if flags.log == "stderr" {
-		quote.Log.SetOutput(os.Stderr)
+		w = os.Stderr
 	} else if flags.log == "discard" {
-		quote.Log.SetOutput(ioutil.Discard)
+		return nil
 	} else {
-		var f *os.File
-		f, err = os.OpenFile(flags.log, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
-		defer f.Close()
-		quote.Log.SetOutput(f)
+		// left open until exit
+		w, err = os.OpenFile(flags.log, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+	}
+
+	level, err := quote.ParseLevel(flags.logLevel)
+	if err != nil {
+		return err
+	}
+	switch flags.logFormat {
+	case "text":
+		c.Log = quote.NewStdLogger(log.New(w, "quote: ", log.Ldate|log.Ltime), level)
+	case "json":
+		c.Log = quote.NewJSONLogger(w, level)
+	default:
+		return fmt.Errorf("invalid log format, must be text or json")
 	}
-	return err
+	return nil
 }
 
 func getSymbols(flags quoteflags, args []string) ([]string, error) {
@@ -196,90 +248,145 @@ func getSymbols(flags quoteflags, args []string) ([]string, error) {
 	}
 
 	// validate outfileFlag
-	if len(symbols) > 1 && flags.outfile != "" && !flags.all {
+	if len(symbols) > 1 && flags.outfile != "" && !flags.all && flags.format != "wide" {
 		return symbols, fmt.Errorf("outfile not valid with multiple symbols\nuse -all=true")
 	}
 
 	return symbols, nil
 }
 
-func getPeriod(periodFlag string) quote.Period {
-	period := quote.Daily
-	switch periodFlag {
-	case "1m":
-		period = quote.Min1
-	case "3m":
-		period = quote.Min3
-	case "5m":
-		period = quote.Min5
-	case "15m":
-		period = quote.Min15
-	case "30m":
-		period = quote.Min30
-	case "1h":
-		period = quote.Min60
-	case "2h":
-		period = quote.Hour2
-	case "4h":
-		period = quote.Hour4
-	case "6h":
-		period = quote.Hour6
-	case "8h":
-		period = quote.Hour8
-	case "12h":
-		period = quote.Hour12
-	case "d":
-		period = quote.Daily
-	case "1d":
-		period = quote.Daily
-	case "3d":
-		period = quote.Day3
-	case "w":
-		period = quote.Weekly
-	case "1w":
-		period = quote.Weekly
-	case "m":
-		period = quote.Monthly
-	case "1M":
-		period = quote.Monthly
-	}
-	return period
+func getLocation(flags quoteflags) (*time.Location, error) {
+	switch strings.ToLower(flags.tz) {
+	case "", "utc":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	case "exchange":
+		return quote.ExchangeLocation(flags.source)
+	}
+	return time.LoadLocation(flags.tz)
+}
+
+func periodList(periods []quote.Period) string {
+	s := make([]string, len(periods))
+	for i, p := range periods {
+		s[i] = "'" + p.String() + "'"
+	}
+	return strings.Join(s, ", ")
 }
 
-func getTimes(flags quoteflags) (time.Time, time.Time) {
+func getTimes(flags quoteflags) (time.Time, time.Time, error) {
 	// determine start/end times
-	to := quote.ParseDateString(flags.end)
+	var err error
+	to := time.Now()
+	if flags.end != "" {
+		to, err = quote.ParseDate(flags.end)
+		if err != nil {
+			return to, to, fmt.Errorf("invalid end: %v", err)
+		}
+	}
 	var from time.Time
 	if flags.start != "" {
-		from = quote.ParseDateString(flags.start)
+		from, err = quote.ParseDate(flags.start)
+		if err != nil {
+			return from, to, fmt.Errorf("invalid start: %v", err)
+		}
 	} else { // use years
 		from = to.Add(-time.Duration(int(time.Hour) * 24 * 365 * flags.years))
 	}
-	return from, to
+	if to.Before(from) {
+		return from, to, fmt.Errorf("end is before start")
+	}
+	return from, to, nil
+}
+
+func newClient(flags quoteflags) (*quote.Client, error) {
+	c := quote.NewClient()
+	c.Delay = time.Duration(flags.delay) * time.Millisecond
+	c.HTTP.Retry.MaxRetries = flags.retries
+	c.HTTP.Retry.MinBackoff = time.Duration(flags.backoff) * time.Millisecond
+	c.TiingoToken = flags.token
+	barTime, err := quote.ParseBarTime(flags.barTime)
+	if err != nil {
+		return nil, err
+	}
+	c.BarTime = barTime
+	c.Precision = int64(flags.precision)
+	c.Exact = flags.exact
+	return c, setOutput(c, flags)
+}
+
+func newSource(c *quote.Client, flags quoteflags) (quote.Source, error) {
+	return quote.NewSource(flags.source, quote.SourceOptions{
+		Token:  flags.token,
+		Client: c,
+	})
+}
+
+func download(ctx context.Context, c *quote.Client, symbols []string, flags quoteflags) (quote.DownloadResults, error) {
+	from, to, err := getTimes(flags)
+	if err != nil {
+		return nil, err
+	}
+	period, err := quote.ParsePeriod(flags.period)
+	if err != nil {
+		return nil, err
+	}
+	src, err := newSource(c, flags)
+	if err != nil {
+		return nil, err
+	}
+	if flags.rate > 0 {
+		c.SetRateLimit(src.Name(), flags.rate, 1)
+	}
+	// periods the source lacks are resampled from a shorter one it has
+	base, err := quote.BasePeriod(src, period)
+	if err != nil {
+		return nil, err
+	}
+	d := quote.NewDownloader(src, flags.workers)
+	d.Client = c
+	results := d.Fetch(ctx, quote.Request{
+		Symbols: symbols,
+		Start:   from,
+		End:     to,
+		Period:  base,
+		// csv and json keep both sets of prices, hs and ami have room for one
+		Adjust: flags.adjust && (flags.format == "hs" || flags.format == "ami"),
+	})
+	if ctx.Err() != nil {
+		return results, ctx.Err()
+	}
+	if base != period {
+		// days, weeks and months of the exchange's calendar
+		loc, err := quote.ExchangeLocation(src.Name())
+		if err != nil {
+			return nil, err
+		}
+		for i := range results {
+			if results[i].Err == nil {
+				results[i].Quote, results[i].Err = quote.Resample(results[i].Quote, period, quote.ResampleOptions{
+					Location:     loc,
+					SessionClose: quote.ExchangeClose(src.Name()),
+				})
+			}
+		}
+	}
+	fmt.Print(results.Summary())
+	return results, nil
 }
 
-func outputAll(symbols []string, flags quoteflags) error {
+func outputAll(ctx context.Context, c *quote.Client, symbols []string, flags quoteflags) error {
 	// output all in one file
-	from, to := getTimes(flags)
-	period := getPeriod(flags.period)
-	quotes := quote.Quotes{}
-	var err error
-	if flags.source == "yahoo" {
-		quotes, err = quote.NewQuotesFromYahooSyms(symbols, from.Format(dateFormat), to.Format(dateFormat), period, flags.adjust)
-	} else if flags.source == "tiingo" {
-		quotes, err = quote.NewQuotesFromTiingoSyms(symbols, from.Format(dateFormat), to.Format(dateFormat), flags.token)
-	} else if flags.source == "tiingo-crypto" {
-		quotes, err = quote.NewQuotesFromTiingoCryptoSyms(symbols, from.Format(dateFormat), to.Format(dateFormat), period, flags.token)
-	} else if flags.source == "coinbase" {
-		quotes, err = quote.NewQuotesFromCoinbaseSyms(symbols, from.Format(dateFormat), to.Format(dateFormat), period)
-	} else if flags.source == "bittrex" {
-		quotes, err = quote.NewQuotesFromBittrexSyms(symbols, period)
-	} else if flags.source == "binance" {
-		quotes, err = quote.NewQuotesFromBinanceSyms(symbols, from.Format(dateFormat), to.Format(dateFormat), period)
+	results, err := download(ctx, c, symbols, flags)
+	if err != nil {
+		return err
 	}
+	loc, err := getLocation(flags)
 	if err != nil {
 		return err
 	}
+	quotes := results.Quotes().In(loc)
 
 	if flags.format == "csv" {
 		err = quotes.WriteCSV(flags.outfile)
@@ -289,31 +396,33 @@ func outputAll(symbols []string, flags quoteflags) error {
 		err = quotes.WriteHighstock(flags.outfile)
 	} else if flags.format == "ami" {
 		err = quotes.WriteAmibroker(flags.outfile)
+	} else if flags.format == "wide" {
+		// leave the dates a symbol has no bar on empty
+		err = quotes.WriteWide(flags.outfile, flags.field, quote.AlignOptions{Join: quote.OuterJoin, Fill: quote.FillNaN})
+	}
+	if err != nil {
+		return err
 	}
-	return err
+	return results.Err()
 }
 
-func outputIndividual(symbols []string, flags quoteflags) error {
+func outputIndividual(ctx context.Context, c *quote.Client, symbols []string, flags quoteflags) error {
 	// output individual symbol files
+	results, err := download(ctx, c, symbols, flags)
+	if err != nil {
+		return err
+	}
 
-	from, to := getTimes(flags)
-	period := getPeriod(flags.period)
+	loc, err := getLocation(flags)
+	if err != nil {
+		return err
+	}
 
-	for _, sym := range symbols {
-		var q quote.Quote
-		if flags.source == "yahoo" {
-			q, _ = quote.NewQuoteFromYahoo(sym, from.Format(dateFormat), to.Format(dateFormat), period, flags.adjust)
-		} else if flags.source == "tiingo" {
-			q, _ = quote.NewQuoteFromTiingo(sym, from.Format(dateFormat), to.Format(dateFormat), flags.token)
-		} else if flags.source == "tiingo-crypto" {
-			q, _ = quote.NewQuoteFromTiingoCrypto(sym, from.Format(dateFormat), to.Format(dateFormat), period, flags.token)
-		} else if flags.source == "coinbase" {
-			q, _ = quote.NewQuoteFromCoinbase(sym, from.Format(dateFormat), to.Format(dateFormat), period)
-		} else if flags.source == "bittrex" {
-			q, _ = quote.NewQuoteFromBittrex(sym, period)
-		} else if flags.source == "binance" {
-			q, _ = quote.NewQuoteFromBinance(sym, from.Format(dateFormat), to.Format(dateFormat), period)
+	for _, r := range results {
+		if r.Err != nil {
+			continue
 		}
+		q := r.Quote.In(loc)
 		var err error
 		if flags.format == "csv" {
 			err = q.WriteCSV(flags.outfile)
@@ -327,24 +436,94 @@ func outputIndividual(symbols []string, flags quoteflags) error {
 		if err != nil {
 			fmt.Printf("Error writing file: %v\n", err)
 		}
-		time.Sleep(quote.Delay * time.Millisecond)
+	}
+	return results.Err()
+}
+
+func outputEvents(ctx context.Context, c *quote.Client, symbols []string, flags quoteflags) error {
+	from, to, err := getTimes(flags)
+	if err != nil {
+		return err
+	}
+	events, err := quote.ParseEvents(flags.events)
+	if err != nil {
+		return err
+	}
+
+	var all []quote.CorporateActions
+	var failed int
+	for _, sym := range symbols {
+		actions, err := c.Actions(ctx, flags.source, sym, from, to, events)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fmt.Printf("  %s: %v\n", sym, err)
+			failed++
+			continue
+		}
+		if flags.all {
+			all = append(all, actions)
+			continue
+		}
+		if flags.format == "json" {
+			err = actions.WriteJSON(flags.outfile, false)
+		} else {
+			err = actions.WriteCSV(flags.outfile)
+		}
+		if err != nil {
+			fmt.Printf("Error writing file: %v\n", err)
+		}
+	}
+
+	if flags.all {
+		if err := writeAllEvents(all, flags); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d symbols failed", failed, len(symbols))
 	}
 	return nil
 }
 
-func handleCommand(cmd string, flags quoteflags) bool {
+func writeAllEvents(all []quote.CorporateActions, flags quoteflags) error {
+	filename := flags.outfile
+	var buffer strings.Builder
+	if flags.format == "json" {
+		if filename == "" {
+			filename = "events.json"
+		}
+		buf, err := json.Marshal(all)
+		if err != nil {
+			return err
+		}
+		buffer.Write(buf)
+	} else {
+		if filename == "" {
+			filename = "events.csv"
+		}
+		buffer.WriteString("symbol,date,event,value\n")
+		for _, actions := range all {
+			csv := actions.CSV()
+			buffer.WriteString(csv[strings.Index(csv, "\n")+1:])
+		}
+	}
+	return os.WriteFile(filename, []byte(buffer.String()), 0644)
+}
+
+func handleCommand(ctx context.Context, c *quote.Client, cmd string, flags quoteflags) (bool, error) {
 
 	// handle market special commands
 	if !quote.ValidMarket(cmd) {
-		return false
+		return false, nil
 	}
 	switch cmd {
 	case "etf":
-		quote.NewEtfFile(flags.outfile)
+		return true, c.EtfFile(ctx, flags.outfile)
 	default:
-		quote.NewMarketFile(cmd, flags.outfile)
+		return true, c.MarketFile(ctx, cmd, flags.outfile)
 	}
-	return true
 }
 
 func main() {
@@ -355,17 +534,29 @@ func main() {
 
 	flag.IntVar(&flags.years, "years", 5, "number of years to download")
 	flag.IntVar(&flags.delay, "delay", 100, "milliseconds to delay between requests")
-	flag.StringVar(&flags.start, "start", "", "start date (yyyy[-mm[-dd]])")
-	flag.StringVar(&flags.end, "end", "", "end date (yyyy[-mm[-dd]])")
-	flag.StringVar(&flags.period, "period", "d", "1m|5m|15m|30m|1h|d")
-	flag.StringVar(&flags.source, "source", "yahoo", "yahoo|tiingo|coinbase|bittrex|binance")
+	flag.IntVar(&flags.workers, "workers", 1, "number of concurrent downloads")
+	flag.Float64Var(&flags.rate, "rate", 0, "max requests per second per source")
+	flag.IntVar(&flags.retries, "retries", quote.DefaultRetryPolicy.MaxRetries, "retries after a transient failure")
+	flag.IntVar(&flags.backoff, "backoff", int(quote.DefaultRetryPolicy.MinBackoff/time.Millisecond), "initial milliseconds between retries")
+	flag.StringVar(&flags.start, "start", "", "start date (yyyy[-mm[-dd]] [hh:mm], rfc3339, epoch, ytd, -30d, ...)")
+	flag.StringVar(&flags.end, "end", "", "end date (yyyy[-mm[-dd]] [hh:mm], rfc3339, epoch, today, -6h, ...)")
+	flag.StringVar(&flags.period, "period", "d", "1m|3m|5m|15m|30m|1h|2h|4h|6h|8h|12h|d|3d|w|m")
+	flag.StringVar(&flags.source, "source", "yahoo", strings.Join(quote.SourceNames(), "|"))
 	flag.StringVar(&flags.token, "token", os.Getenv("TIINGO_API_TOKEN"), "tiingo api token")
 	flag.StringVar(&flags.infile, "infile", "", "input filename")
 	flag.StringVar(&flags.outfile, "outfile", "", "output filename")
-	flag.StringVar(&flags.format, "format", "csv", "csv|json")
+	flag.StringVar(&flags.format, "format", "csv", "csv|json|hs|ami|wide")
+	flag.StringVar(&flags.field, "field", "close", "column of -format=wide")
+	flag.StringVar(&flags.tz, "tz", "utc", "utc|local|exchange|<zone name>")
+	flag.StringVar(&flags.barTime, "bartime", "open", "stamp bars with their open|close time")
+	flag.IntVar(&flags.precision, "precision", 0, "decimal places of prices, 0 for the source's")
+	flag.BoolVar(&flags.exact, "exact", false, "write prices exactly as the exchange sent them")
+	flag.StringVar(&flags.events, "events", "", "download div|split|all events instead of prices")
 	flag.StringVar(&flags.log, "log", "stdout", "<filename>|stdout")
+	flag.StringVar(&flags.logLevel, "loglevel", "info", "debug|info|warn|error")
+	flag.StringVar(&flags.logFormat, "logformat", "text", "text|json")
 	flag.BoolVar(&flags.all, "all", false, "all output in one file")
-	flag.BoolVar(&flags.adjust, "adjust", true, "adjust Yahoo prices")
+	flag.BoolVar(&flags.adjust, "adjust", true, "adjusted Yahoo and Tiingo prices as open..close in hs|ami")
 	flag.BoolVar(&flags.version, "v", false, "show version")
 	flag.BoolVar(&flags.version, "version", false, "show version")
 	flag.Parse()
@@ -375,9 +566,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	quote.Delay = time.Duration(flags.delay)
-
-	err = setOutput(flags)
+	client, err := newClient(flags)
 	check(err)
 
 	err = checkFlags(flags)
@@ -386,15 +575,33 @@ func main() {
 	symbols, err = getSymbols(flags, flag.Args())
 	check(err)
 
+	// stop downloading on interrupt
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	// check for and handled special commands
-	if handleCommand(symbols[0], flags) {
+	if handled, err := handleCommand(ctx, client, symbols[0], flags); handled {
+		if err != nil {
+			fmt.Printf("\nerror: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
 	// main output
-	if flags.all {
-		err = outputAll(symbols, flags)
+	if flags.events != "" {
+		err = outputEvents(ctx, client, symbols, flags)
+	} else if flags.all || flags.format == "wide" {
+		// a wide table has every symbol in one file
+		err = outputAll(ctx, client, symbols, flags)
 	} else {
-		err = outputIndividual(symbols, flags)
+		err = outputIndividual(ctx, client, symbols, flags)
+	}
+	if err != nil {
+		// failed symbols are already listed in the download summary
+		if _, ok := err.(*quote.DownloadError); !ok {
+			fmt.Printf("\nerror: %v\n", err)
+		}
+		os.Exit(1)
 	}
 }
\ No newline at end of file
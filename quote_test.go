@@ -2,11 +2,23 @@
 package quote
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // assert fails the test if the condition is false.
@@ -98,4 +110,1313 @@ aapl,2018-07-20 00:00,191.78,192.43,190.17,188.57,20676200.00`
 	if q[1].Close[len(q[1].Close)-1] != 188.57 {
 		t.Error("Invalid last value")
 	}
-}
\ No newline at end of file
+}
+
+func TestTimeZones(t *testing.T) {
+	q := NewQuote("spy", 2)
+	q.Date[0] = time.Date(2021, 3, 15, 14, 30, 0, 0, time.UTC)
+	q.Date[1] = time.Date(2021, 3, 15, 14, 31, 0, 0, time.UTC)
+	est := time.FixedZone("EST", -5*60*60)
+
+	csv := q.In(est).CSV()
+	assert(t, strings.Contains(csv, "\n2021-03-15 09:30 -05:00,"), "expected offset in csv:\n%s", csv)
+	back, err := NewQuoteFromCSV("spy", csv)
+	ok(t, err)
+	equals(t, q.Date, back.Date)
+	assert(t, strings.Contains(q.CSV(), "\n2021-03-15 14:30 +00:00,"), "expected utc in csv:\n%s", q.CSV())
+
+	jsn := q.In(est).JSON(false)
+	assert(t, strings.Contains(jsn, `"2021-03-15T09:30:00-05:00"`), "expected offset in json: %s", jsn)
+	back, err = NewQuoteFromJSON(jsn)
+	ok(t, err)
+	equals(t, q.Date, back.Date)
+
+	loc, err := ExchangeLocation("binance")
+	ok(t, err)
+	equals(t, time.UTC, loc)
+}
+
+// testClient - a Client sending the requests of every http source to a
+// test server running handler, with no delay between requests
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	c := NewClient()
+	c.Delay = 0
+	c.HTTP.Client = ts.Client()
+	c.HTTP.BaseURLs = make(map[string]string)
+	for name := range DefaultBaseURLs {
+		if name != "nasdaq-ftp" {
+			c.HTTP.BaseURLs[name] = ts.URL
+		}
+	}
+	return c
+}
+
+// day - midnight UTC on day d of March 2021
+func day(d int) time.Time { return time.Date(2021, 3, d, 0, 0, 0, 0, time.UTC) }
+
+// bars - daily bars of symbol on the given days of March 2021, each
+// priced at its day of the month with a volume of 100
+func bars(symbol string, days ...int) Quote {
+	q := NewQuote(symbol, len(days))
+	for i, d := range days {
+		q.Date[i] = day(d)
+		q.Open[i], q.High[i], q.Low[i], q.Close[i], q.Volume[i] = float64(d), float64(d), float64(d), float64(d), 100
+	}
+	return q
+}
+
+func TestBarTime(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[[1615816800000,"100.0","110.0","90.0","105.0","12.5",1615820399999,"0",1,"0","0","0"]]`)
+	})
+	open := time.Date(2021, 3, 15, 14, 0, 0, 0, time.UTC)
+
+	q, err := c.QuoteFromBinance(context.Background(), "BTCUSDT", "2021-03-15 14:00", "2021-03-15 15:00", Min60)
+	ok(t, err)
+	equals(t, []time.Time{open}, q.Date)
+	equals(t, BarOpen, q.BarTime)
+
+	c.BarTime = BarClose
+	q, err = c.QuoteFromBinance(context.Background(), "BTCUSDT", "2021-03-15 14:00", "2021-03-15 15:00", Min60)
+	ok(t, err)
+	equals(t, []time.Time{open.Add(time.Hour)}, q.Date)
+	equals(t, BarClose, q.BarTime)
+
+	csv := q.CSV()
+	assert(t, strings.HasPrefix(csv, "closetime,"), "expected closetime header:\n%s", csv)
+	back, err := NewQuoteFromCSV("BTCUSDT", csv)
+	ok(t, err)
+	equals(t, BarClose, back.BarTime)
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, BarClose, back.BarTime)
+	equals(t, BarClose, c.stamp(q, Min60).BarTime)
+	equals(t, q.Date, c.stamp(q, Min60).Date)
+
+	// daily bars are exchange sessions, dated in the exchange's zone
+	// and closing at its session close
+	c = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Date,Open,High,Low,Close,Adj Close,Volume\n2021-03-15,395,397,393,396,394,100\n")
+	})
+	ny, err := ExchangeLocation("yahoo")
+	ok(t, err)
+	q, err = c.QuoteFromYahoo(context.Background(), "spy", "2021-03-15", "2021-03-16", Daily, true)
+	ok(t, err)
+	equals(t, []time.Time{time.Date(2021, 3, 15, 4, 0, 0, 0, time.UTC)}, q.Date)
+	assert(t, strings.Contains(q.In(ny).CSV(), "\n2021-03-15 00:00 -04:00,"), "expected the session date in New York:\n%s", q.In(ny).CSV())
+
+	c.BarTime = BarClose
+	q, err = c.QuoteFromYahoo(context.Background(), "spy", "2021-03-15", "2021-03-16", Daily, true)
+	ok(t, err)
+	assert(t, strings.Contains(q.In(ny).CSV(), "\n2021-03-15 16:00 -04:00,"), "expected the session close in New York:\n%s", q.In(ny).CSV())
+}
+
+func TestActions(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/tiingo/"):
+			fmt.Fprint(w, `[{"date":"2020-08-07T00:00:00.000Z","close":444.45,"divCash":0.82,"splitFactor":1.0},
+				{"date":"2020-08-28T00:00:00.000Z","close":499.23,"divCash":0.0,"splitFactor":1.0},
+				{"date":"2020-08-31T00:00:00.000Z","close":129.04,"divCash":0.0,"splitFactor":4.0}]`)
+		case r.URL.Query().Get("events") == "div":
+			fmt.Fprint(w, "Date,Dividends\n2020-11-06,0.205\n2020-08-07,0.82\n")
+		case r.URL.Query().Get("events") == "split":
+			fmt.Fprint(w, "Date,Stock Splits\n2020-08-31,4:1\n")
+		}
+	})
+	from := time.Date(2020, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC)
+	// session dates start at midnight in New York
+	aug7 := time.Date(2020, 8, 7, 4, 0, 0, 0, time.UTC)
+	aug31 := time.Date(2020, 8, 31, 4, 0, 0, 0, time.UTC)
+
+	a, err := c.Actions(context.Background(), "tiingo", "aapl", from, to, AllEvents)
+	ok(t, err)
+	equals(t, []Dividend{{aug7, 0.82}}, a.Dividends)
+	equals(t, []Split{{aug31, 4}}, a.Splits)
+
+	a, err = c.Actions(context.Background(), "yahoo", "aapl", from, to, AllEvents)
+	ok(t, err)
+	equals(t, 2, len(a.Dividends))
+	equals(t, Dividend{aug7, 0.82}, a.Dividends[0])
+	equals(t, []Split{{aug31, 4}}, a.Splits)
+
+	back, err := NewActionsFromCSV(a.CSV())
+	ok(t, err)
+	equals(t, a, back)
+	back, err = NewActionsFromJSON(a.JSON(false))
+	ok(t, err)
+	equals(t, a, back)
+
+	a, err = c.Actions(context.Background(), "yahoo", "aapl", from, to, Splits)
+	ok(t, err)
+	equals(t, 0, len(a.Dividends))
+
+	_, err = c.Actions(context.Background(), "binance", "BTCUSDT", from, to, AllEvents)
+	assert(t, err != nil, "expected binance to have no corporate actions")
+
+	events, err := ParseEvents("div, split")
+	ok(t, err)
+	equals(t, AllEvents, events)
+	_, err = ParseEvents("earnings")
+	assert(t, err != nil, "expected an invalid event error")
+}
+
+func TestAdjust(t *testing.T) {
+	q := NewQuote("aapl", 4)
+	q.Date = []time.Time{day(3), day(4), day(5), day(6)}
+	q.Open = []float64{10, 9, 4.5, 5}
+	q.High = []float64{10, 9, 4.5, 5}
+	q.Low = []float64{10, 9, 4.5, 5}
+	q.Close = []float64{10, 9, 4.5, 5}
+	q.Volume = []float64{100, 100, 200, 200}
+	actions := CorporateActions{
+		Symbol:    "aapl",
+		Dividends: []Dividend{{day(4), 1}, {day(1), 5}},
+		Splits:    []Split{{day(5), 2}},
+	}
+
+	adj, err := Adjust(q, actions, AdjustSplits)
+	ok(t, err)
+	equals(t, []float64{5, 4.5, 4.5, 5}, adj.Close)
+	equals(t, []float64{200, 200, 200, 200}, adj.Volume)
+	equals(t, 10.0, q.Close[0])
+
+	adj, err = Adjust(q, actions, AdjustDividends)
+	ok(t, err)
+	equals(t, []float64{4.5, 4.5, 4.5, 5}, adj.Close)
+	equals(t, []float64{200, 200, 200, 200}, adj.Volume)
+
+	adj, err = Adjust(q, actions, AdjustTotalReturn)
+	ok(t, err)
+	equals(t, []float64{10, 10, 10, 5 / 0.45}, adj.Close)
+	equals(t, []float64{100, 100, 100, 100}, adj.Volume)
+
+	q.BarTime = BarClose
+	adj, err = Adjust(q, actions, AdjustSplits)
+	ok(t, err)
+	equals(t, []float64{5, 4.5, 2.25, 5}, adj.Close)
+
+	_, err = Adjust(q, CorporateActions{Symbol: "msft"}, AdjustSplits)
+	assert(t, err != nil, "expected a symbol mismatch error")
+
+	// a 2:1 split before a dividend, from raw bars and from Yahoo's split
+	// adjusted bars with the dividends alone
+	raw := NewQuote("aapl", 4)
+	raw.Date = []time.Time{day(3), day(4), day(5), day(6)}
+	raw.Close = []float64{20, 10, 9, 9}
+	raw.Open, raw.High, raw.Low = raw.Close, raw.Close, raw.Close
+	raw.Volume = []float64{100, 200, 200, 200}
+	actions = CorporateActions{Symbol: "aapl", Dividends: []Dividend{{day(5), 1}}, Splits: []Split{{day(4), 2}}}
+	adj, err = Adjust(raw, actions, AdjustDividends)
+	ok(t, err)
+	equals(t, []float64{9, 9, 9, 9}, adj.Close)
+	equals(t, []float64{200, 200, 200, 200}, adj.Volume)
+
+	yahoo := raw.Slice(0, raw.Len())
+	yahoo.Close = []float64{10, 10, 9, 9}
+	yahoo.Open, yahoo.High, yahoo.Low = yahoo.Close, yahoo.Close, yahoo.Close
+	yahoo.Volume = []float64{200, 200, 200, 200}
+	adj, err = Adjust(yahoo, CorporateActions{Symbol: "aapl", Dividends: actions.Dividends}, AdjustDividends)
+	ok(t, err)
+	equals(t, []float64{9, 9, 9, 9}, adj.Close)
+	equals(t, []float64{200, 200, 200, 200}, adj.Volume)
+}
+
+func TestAdjustedColumns(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"date":"2020-08-31T00:00:00.000Z","open":127.58,"high":131,"low":126,"close":129.04,"volume":225702700,
+			"adjOpen":125.3,"adjHigh":128.66,"adjLow":123.75,"adjClose":126.74,"adjVolume":225702700,"divCash":0,"splitFactor":4}]`)
+	})
+	src, err := NewSource("tiingo", SourceOptions{Client: c})
+	ok(t, err)
+	quotes, err := src.Fetch(context.Background(), Request{Symbols: []string{"aapl"}, Limit: 1})
+	ok(t, err)
+	q := quotes[0]
+	equals(t, 129.04, q.Close[0])
+	equals(t, 126.74, q.AdjClose[0])
+	equals(t, 225702700.0, q.AdjVolume[0])
+
+	csv := q.CSV()
+	assert(t, strings.HasPrefix(csv, "datetime,open,high,low,close,volume,adjopen,adjhigh,adjlow,adjclose,adjvolume\n"), "unexpected header:\n%s", csv)
+	back, err := NewQuoteFromCSV("aapl", csv)
+	ok(t, err)
+	equals(t, q, back)
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, q, back)
+
+	// only the columns every quote has are written
+	yahoo := NewQuote("spy", 1)
+	yahoo.Date[0] = q.Date[0]
+	yahoo.AdjClose = []float64{340}
+	many, err := NewQuotesFromCSV(Quotes{q, yahoo}.CSV())
+	ok(t, err)
+	equals(t, []float64{126.74}, many[0].AdjClose)
+	equals(t, []float64{340.0}, many[1].AdjClose)
+	equals(t, []float64(nil), many[0].AdjOpen)
+	many, err = NewQuotesFromJSON(Quotes{q, yahoo}.JSON(false))
+	ok(t, err)
+	equals(t, Quotes{q, yahoo}, many)
+
+	// raw prices are kept on the default path, Adjusted swaps in the others
+	q, err = c.QuoteFromTiingo(context.Background(), "aapl", "2020-08-31", "2020-09-01")
+	ok(t, err)
+	equals(t, 129.04, q.Close[0])
+	equals(t, 126.74, q.AdjClose[0])
+	adjusted := q.Adjusted()
+	equals(t, []float64{125.3}, adjusted.Open)
+	equals(t, []float64{126.74}, adjusted.Close)
+	equals(t, []float64(nil), adjusted.AdjClose)
+	equals(t, 129.04, q.Close[0])
+
+	// or asked for with Adjust, in the request or the source
+	quotes, err = src.Fetch(context.Background(), Request{Symbols: []string{"aapl"}, Limit: 1, Adjust: true})
+	ok(t, err)
+	equals(t, adjusted, quotes[0])
+	src, err = NewSource("tiingo", SourceOptions{Client: c, Adjust: true})
+	ok(t, err)
+	quotes, err = src.Fetch(context.Background(), Request{Symbols: []string{"aapl"}, Limit: 1})
+	ok(t, err)
+	equals(t, adjusted, quotes[0])
+
+	_, err = NewQuoteFromCSV("aapl", "datetime,open,high,low,close,volume,vwap\n")
+	assert(t, err != nil, "expected an unknown column error")
+
+	// the package functions keep returning adjusted prices in Close
+	saved := HTTP
+	defer func() { HTTP = saved }()
+	HTTP = testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/tiingo/") {
+			fmt.Fprint(w, `[{"date":"2020-08-31T00:00:00.000Z","close":129.04,"adjClose":126.74}]`)
+			return
+		}
+		fmt.Fprint(w, "Date,Open,High,Low,Close,Adj Close,Volume\n2021-03-15,395,397,393,396,394,100\n")
+	}).HTTP
+	q, err = NewQuoteFromYahoo("spy", "2021-03-15", "2021-03-16", Daily, true)
+	ok(t, err)
+	equals(t, []float64{394}, q.Close)
+	equals(t, []float64(nil), q.AdjClose)
+	q, err = NewQuoteFromYahoo("spy", "2021-03-15", "2021-03-16", Daily, false)
+	ok(t, err)
+	equals(t, []float64{396}, q.Close)
+	equals(t, []float64{394}, q.AdjClose)
+	q, err = NewQuoteFromTiingo("aapl", "2020-08-31", "2020-09-01", "secret")
+	ok(t, err)
+	equals(t, []float64{126.74}, q.Close)
+}
+
+func TestCryptoColumns(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/tiingo/") {
+			fmt.Fprint(w, `[{"ticker":"btcusd","priceData":[{"date":"2021-03-15T00:00:00Z","open":1,"high":2,"low":0.5,"close":1.5,
+				"volume":10,"volumeNotional":15,"tradesDone":42}]}]`)
+			return
+		}
+		fmt.Fprint(w, `[[1615766400000,"100.0","110.0","90.0","105.0","12.5",1615852799999,"1300.5",321,"6.25","650.25","0"]]`)
+	})
+
+	q, err := c.QuoteFromBinance(context.Background(), "BTCUSDT", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, []float64{1300.5}, q.QuoteVolume)
+	equals(t, []float64{321}, q.Trades)
+	equals(t, []float64{6.25}, q.TakerBuyVolume)
+	equals(t, []float64{650.25}, q.TakerBuyQuoteVolume)
+	equals(t, []float64(nil), q.AdjClose)
+
+	csv := q.CSV()
+	assert(t, strings.Contains(csv, ",1300.50000000,321,6.25000000,650.25000000\n"), "unexpected csv:\n%s", csv)
+	back, err := NewQuoteFromCSV("BTCUSDT", csv)
+	ok(t, err)
+	equals(t, q, back)
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, q, back)
+
+	q, err = c.QuoteFromTiingoCrypto(context.Background(), "btcusd", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, []float64{15}, q.QuoteVolume)
+	equals(t, []float64{42}, q.Trades)
+	equals(t, []float64(nil), q.TakerBuyVolume)
+
+	// a column only some pages have is dropped
+	var all Quote
+	all.append(q)
+	equals(t, []float64{42}, all.Trades)
+	all.append(NewQuote("btcusd", 1))
+	equals(t, []float64(nil), all.Trades)
+	equals(t, 2, len(all.Close))
+}
+
+func TestPrecision(t *testing.T) {
+	var infoRequests int
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/exchangeInfo":
+			infoRequests++
+			fmt.Fprint(w, `{"symbols":[{"symbol":"DOGEUSDT","filters":[{"filterType":"PRICE_FILTER","tickSize":"0.00001000"},{"filterType":"LOT_SIZE","stepSize":"1.00000000"}]}]}`)
+		case "/api/v1/klines":
+			fmt.Fprint(w, `[[1615766400000,"0.05612","0.0600","0.0500","0.05789","12.5",1615852799999,"0",1,"0","0","0"]]`)
+		case "/products/BTC-USD":
+			fmt.Fprint(w, `{"id":"BTC-USD","quote_increment":"0.01000000"}`)
+		case "/products/BTC-JPY":
+			fmt.Fprint(w, `{"id":"BTC-JPY","quote_increment":"1"}`)
+		default:
+			fmt.Fprint(w, `[[1615766400,50000.1,60000.2,55000.3,58000.4,12.5]]`)
+		}
+	})
+
+	q, err := c.QuoteFromBinance(context.Background(), "DOGEUSDT", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, int64(5), q.Precision)
+	assert(t, strings.Contains(q.CSV(), ",0.05612,"), "expected 5 decimals:\n%s", q.CSV())
+	back, err := NewQuoteFromCSV("DOGEUSDT", q.CSV())
+	ok(t, err)
+	equals(t, int64(5), back.Precision)
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, int64(5), back.Precision)
+
+	_, err = c.QuoteFromBinance(context.Background(), "DOGEUSDT", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, 1, infoRequests)
+
+	q, err = c.QuoteFromCoinbase(context.Background(), "BTC-USD", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, int64(2), q.Precision)
+
+	// volumes keep their own decimals whatever the price tick
+	equals(t, int64(8), q.VolumePrecision)
+	assert(t, strings.Contains(q.CSV(), ",58000.40,12.50000000\n"), "expected 8 volume decimals:\n%s", q.CSV())
+	back, err = NewQuoteFromCSV("BTC-USD", q.CSV())
+	ok(t, err)
+	equals(t, q.VolumePrecision, back.VolumePrecision)
+	equals(t, q.Precision, back.Precision)
+	wide, err := Quotes{q}.Wide("volume", AlignOptions{})
+	ok(t, err)
+	assert(t, strings.HasSuffix(wide, ",12.50000000\n"), "expected 8 volume decimals:\n%s", wide)
+
+	// a whole number tick means no decimals, not the default
+	q, err = c.QuoteFromCoinbase(context.Background(), "BTC-JPY", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, int64(NoDecimals), q.Precision)
+	assert(t, strings.Contains(q.CSV(), ",50000,60000,55000,58000,12.50000000\n"), "expected no price decimals:\n%s", q.CSV())
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, q.Precision, back.Precision)
+
+	// callers override the source, per client or per quote
+	c.Precision = 4
+	q, err = c.QuoteFromCoinbase(context.Background(), "BTC-USD", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, int64(4), q.Precision)
+	q.Precision = 1
+	assert(t, strings.Contains(q.CSV(), ",50000.1,"), "expected 1 decimal:\n%s", q.CSV())
+
+	equals(t, DefaultPrecision, NewQuote("spy", 0).precision())
+	equals(t, int64(0), decimals("1"))
+	equals(t, int64(8), decimals("0.00000001"))
+}
+
+func TestExact(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[[1615766400000,"0.05612000","0.06000000","0.05000000","0.05789000","12.50000000",1615852799999,"0",1,"0","0","0"]]`)
+	})
+	c.Precision = 2
+	c.Exact = true
+
+	q, err := c.QuoteFromBinance(context.Background(), "DOGEUSDT", "2021-03-15", "2021-03-16", Daily)
+	ok(t, err)
+	equals(t, []ExactBar{{"0.05612000", "0.06000000", "0.05000000", "0.05789000", "12.50000000"}}, q.Exact)
+	equals(t, 0.05612, q.Exact[0].Open.Float64())
+
+	csv := q.CSV()
+	assert(t, strings.Contains(csv, ",0.05612000,0.06000000,0.05000000,0.05789000,12.50000000,"), "expected exact prices:\n%s", csv)
+	back, err := NewQuoteFromCSVExact("DOGEUSDT", csv)
+	ok(t, err)
+	equals(t, q.Exact, back.Exact)
+	back, err = NewQuoteFromJSON(q.JSON(false))
+	ok(t, err)
+	equals(t, q, back)
+	many, err := NewQuotesFromCSVExact(Quotes{q}.CSV())
+	ok(t, err)
+	equals(t, q.Exact, many[0].Exact)
+
+	// bars without exchange decimals are filled from the floats
+	q.append(NewQuote("DOGEUSDT", 1))
+	equals(t, ExactBar{"0", "0", "0", "0", "0"}, q.Exact[1])
+	q = q.Slice(0, 1)
+	q.Exact = nil
+	assert(t, strings.Contains(q.CSV(), ",0.06,0.05,0.06,12.50"), "expected rounded prices:\n%s", q.CSV())
+}
+
+func TestBars(t *testing.T) {
+	q := NewQuote("spy", 5)
+	for i := range q.Date {
+		q.Date[i] = day(i + 1)
+		q.Open[i], q.High[i], q.Low[i], q.Close[i], q.Volume[i] = float64(i), float64(i+2), float64(i-1), float64(i+1), 100
+	}
+	q.AdjClose = []float64{1, 2, 3, 4, 5}
+
+	equals(t, 5, q.Len())
+	equals(t, []time.Time{day(4), day(5)}, q.Last(2).Date)
+	equals(t, []float64{4, 5}, q.Last(2).AdjClose)
+	equals(t, 5, q.Last(10).Len())
+	equals(t, []float64{2, 3}, q.Slice(1, 3).Close)
+
+	between := q.Between(day(2).Add(time.Hour), day(4))
+	equals(t, []time.Time{day(3), day(4)}, between.Date)
+	equals(t, []float64{3, 4}, between.AdjClose)
+	equals(t, 0, q.Between(day(4), day(2)).Len())
+
+	equals(t, 2, q.IndexOf(day(3)))
+	equals(t, -1, q.IndexOf(day(3).Add(time.Minute)))
+	b, found := q.At(day(3))
+	assert(t, found, "expected a bar on day 3")
+	equals(t, Bar{Date: day(3), Open: 2, High: 4, Low: 1, Close: 3, Volume: 100}, b)
+	_, found = q.At(day(9))
+	assert(t, !found, "expected no bar on day 9")
+
+	var closes []float64
+	it := q.Bars()
+	for it.Next() {
+		closes = append(closes, it.Bar().Close)
+		equals(t, len(closes)-1, it.Index())
+	}
+	equals(t, q.Close, closes)
+	assert(t, !it.Next(), "expected an exhausted iterator to stay exhausted")
+}
+
+func TestMerge(t *testing.T) {
+	history := bars("spy", 1, 2, 3)
+	tail := bars("spy", 5, 3, 4)
+	tail.Close[1] = 30
+
+	m, err := history.Merge(tail, PreferNewer)
+	ok(t, err)
+	equals(t, []time.Time{day(1), day(2), day(3), day(4), day(5)}, m.Date)
+	equals(t, []float64{1, 2, 30, 4, 5}, m.Close)
+	equals(t, 3, history.Len())
+
+	m, err = history.Merge(tail, PreferOlder)
+	ok(t, err)
+	equals(t, []float64{1, 2, 3, 4, 5}, m.Close)
+
+	_, err = history.Merge(tail, ErrorOnConflict)
+	assert(t, err != nil, "expected a conflict on day 3")
+	m, err = history.Merge(history.Last(2), ErrorOnConflict)
+	ok(t, err)
+	equals(t, history, m)
+
+	// duplicates within one quote, and optional columns only one has
+	dup := bars("spy", 2, 2)
+	dup.Close = []float64{10, 20}
+	dup.AdjClose = []float64{9, 19}
+	m, err = dup.Merge(Quote{}, PreferNewer)
+	ok(t, err)
+	equals(t, []float64{20}, m.Close)
+	equals(t, []float64{19}, m.AdjClose)
+	m, err = history.Merge(dup, PreferOlder)
+	ok(t, err)
+	equals(t, []float64{1, 2, 3}, m.Close)
+	equals(t, []float64(nil), m.AdjClose)
+
+	_, err = history.Merge(NewQuote("qqq", 1), PreferNewer)
+	assert(t, err != nil, "expected a symbol mismatch")
+}
+
+func TestResample(t *testing.T) {
+	hourly := NewQuote("BTCUSDT", 10)
+	start := time.Date(2021, 3, 15, 22, 0, 0, 0, time.UTC)
+	for i := range hourly.Date {
+		hourly.Date[i] = start.Add(time.Duration(i) * time.Hour)
+		hourly.Open[i], hourly.High[i], hourly.Low[i], hourly.Close[i], hourly.Volume[i] = float64(i), float64(i+5), float64(i-5), float64(i+1), 1
+	}
+	hourly.Trades = []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+
+	r, err := Resample(hourly, Hour4, ResampleOptions{})
+	ok(t, err)
+	equals(t, []time.Time{start.Add(-2 * time.Hour), start.Add(2 * time.Hour), start.Add(6 * time.Hour)}, r.Date)
+	equals(t, []float64{0, 2, 6}, r.Open)
+	equals(t, []float64{6, 10, 14}, r.High)
+	equals(t, []float64{-5, -3, 1}, r.Low)
+	equals(t, []float64{2, 6, 10}, r.Close)
+	equals(t, []float64{2, 4, 4}, r.Volume)
+	equals(t, []float64{2, 4, 4}, r.Trades)
+
+	// days starting at 23:00
+	r, err = Resample(hourly, Daily, ResampleOptions{SessionStart: 23 * time.Hour})
+	ok(t, err)
+	equals(t, []time.Time{start.Add(-23 * time.Hour), start.Add(time.Hour)}, r.Date)
+	equals(t, []float64{1, 9}, r.Volume)
+
+	// close stamped bars stay close stamped
+	closed := hourly
+	closed.BarTime = BarClose
+	r, err = Resample(closed, Hour4, ResampleOptions{})
+	ok(t, err)
+	equals(t, []time.Time{start.Add(2 * time.Hour), start.Add(6 * time.Hour), start.Add(10 * time.Hour)}, r.Date)
+	equals(t, []float64{3, 4, 3}, r.Volume)
+
+	daily := NewQuote("spy", 40)
+	for i := range daily.Date {
+		daily.Date[i] = time.Date(2021, 2, 15+i, 0, 0, 0, 0, time.UTC) // Monday
+		daily.Close[i] = float64(i)
+		daily.Volume[i] = 1
+	}
+	r, err = Resample(daily, Weekly, ResampleOptions{})
+	ok(t, err)
+	equals(t, time.Date(2021, 2, 15, 0, 0, 0, 0, time.UTC), r.Date[0])
+	equals(t, []float64{6, 13}, r.Close[:2])
+	r, err = Resample(daily, Weekly, ResampleOptions{WeekEnd: time.Friday})
+	ok(t, err)
+	equals(t, time.Date(2021, 2, 13, 0, 0, 0, 0, time.UTC), r.Date[0])
+	equals(t, []float64{5, 7}, r.Volume[:2])
+	r, err = Resample(daily, Monthly, ResampleOptions{})
+	ok(t, err)
+	equals(t, []time.Time{time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)}, r.Date[:2])
+	equals(t, []float64{14, 26}, r.Volume)
+
+	// close stamped sessions keep the exchange's close
+	ny, err := time.LoadLocation("America/New_York")
+	ok(t, err)
+	sessions := NewQuote("spy", 6)
+	for i, d := range []int{1, 2, 3, 4, 5, 8} {
+		sessions.Date[i] = time.Date(2021, 3, d, 16, 0, 0, 0, ny)
+		sessions.Close[i] = float64(d)
+	}
+	sessions.BarTime = BarClose
+	r, err = Resample(sessions, Weekly, ResampleOptions{Location: ny, SessionClose: ExchangeClose("yahoo")})
+	ok(t, err)
+	equals(t, []time.Time{sessions.Date[4], sessions.Date[5]}, r.Date)
+	equals(t, []float64{5, 8}, r.Close)
+	r, err = Resample(sessions, Monthly, ResampleOptions{Location: ny, SessionClose: ExchangeClose("yahoo")})
+	ok(t, err)
+	equals(t, []time.Time{sessions.Date[5]}, r.Date)
+	r, err = Resample(sessions, Weekly, ResampleOptions{Location: ny})
+	ok(t, err)
+	equals(t, time.Date(2021, 3, 8, 0, 0, 0, 0, ny), r.Date[0])
+
+	_, err = Resample(daily, Min60, ResampleOptions{})
+	assert(t, err != nil, "expected an error resampling to shorter bars")
+
+	src, err := NewSource("coinbase", SourceOptions{})
+	ok(t, err)
+	base, err := BasePeriod(src, Hour4)
+	ok(t, err)
+	equals(t, Min60, base)
+	base, err = BasePeriod(src, Monthly)
+	ok(t, err)
+	equals(t, Daily, base)
+	base, err = BasePeriod(src, Weekly)
+	ok(t, err)
+	equals(t, Daily, base)
+	base, err = BasePeriod(src, Hour12)
+	ok(t, err)
+	equals(t, Hour6, base)
+	src, err = NewSource("yahoo", SourceOptions{})
+	ok(t, err)
+	_, err = BasePeriod(src, Min60)
+	assert(t, errors.Is(err, ErrInvalidPeriod), "expected ErrInvalidPeriod, got %v", err)
+}
+
+func TestAlign(t *testing.T) {
+	spy := bars("spy", 1, 2, 3, 5)
+	btc := bars("btc", 2, 3, 4, 5, 6)
+	btc.AdjClose = []float64{20, 30, 40, 50, 60}
+	quotes := Quotes{spy, btc}
+
+	aligned, dates, err := quotes.Align(AlignOptions{Join: InnerJoin})
+	ok(t, err)
+	equals(t, []time.Time{day(2), day(3), day(5)}, dates)
+	equals(t, dates, aligned[0].Date)
+	equals(t, []float64{2, 3, 5}, aligned[0].Close)
+	equals(t, []float64{20, 30, 50}, aligned[1].AdjClose)
+
+	aligned, dates, err = quotes.Align(AlignOptions{Join: OuterJoin, Fill: FillForward})
+	ok(t, err)
+	equals(t, 6, len(dates))
+	equals(t, []float64{1, 2, 3, 3, 5, 5}, aligned[0].Close)
+	equals(t, []float64{100, 100, 100, 0, 100, 0}, aligned[0].Volume)
+	assert(t, math.IsNaN(aligned[1].Close[0]), "expected NaN before btc's first bar")
+	equals(t, []float64{2, 3, 4, 5, 6}, aligned[1].Close[1:])
+
+	aligned, dates, err = quotes.Align(AlignOptions{Join: ReferenceJoin, Reference: "spy", Fill: FillNaN})
+	ok(t, err)
+	equals(t, spy.Date, dates)
+	equals(t, spy, aligned[0])
+	assert(t, math.IsNaN(aligned[1].Close[0]) && math.IsNaN(aligned[1].Volume[0]), "expected NaN for btc on day 1")
+	assert(t, math.IsNaN(aligned[1].AdjClose[0]), "expected NaN adjusted close for btc on day 1")
+
+	_, dates, err = quotes.Align(AlignOptions{Join: OuterJoin, Fill: FillDrop})
+	ok(t, err)
+	equals(t, []time.Time{day(2), day(3), day(5)}, dates)
+
+	_, _, err = quotes.Align(AlignOptions{Join: ReferenceJoin, Reference: "qqq"})
+	assert(t, err != nil, "expected an unknown reference error")
+
+	// a bar off the timeline still carries forward
+	a, ref := bars("a", 1, 2, 4), bars("ref", 1, 3)
+	aligned, _, err = Quotes{a, ref}.Align(AlignOptions{Join: ReferenceJoin, Reference: "ref", Fill: FillForward})
+	ok(t, err)
+	equals(t, []float64{1, 2}, aligned[0].Close)
+}
+
+func TestWide(t *testing.T) {
+	spy := NewQuote("spy", 2)
+	spy.Date = []time.Time{day(1), day(3)}
+	spy.Close = []float64{390.5, 392.25}
+	spy.AdjClose = []float64{388.1, 390}
+	qqq := NewQuote("qqq", 2)
+	qqq.Date = []time.Time{day(2), day(3)}
+	qqq.Close = []float64{310, 312.75}
+	qqq.AdjClose = []float64{309, 311}
+	quotes := Quotes{spy, qqq}
+
+	wide, err := quotes.Wide("close", AlignOptions{Join: OuterJoin, Fill: FillNaN})
+	ok(t, err)
+	equals(t, "datetime,spy,qqq\n"+
+		"2021-03-01 00:00 +00:00,390.50,\n"+
+		"2021-03-02 00:00 +00:00,,310.00\n"+
+		"2021-03-03 00:00 +00:00,392.25,312.75\n", wide)
+
+	closes, err := NewQuotesFromWide(wide, "close")
+	ok(t, err)
+	equals(t, 2, len(closes))
+	equals(t, "qqq", closes[1].Symbol)
+	equals(t, spy.Date, closes[0].Date)
+	equals(t, spy.Close, closes[0].Close)
+	equals(t, qqq.Close, closes[1].Close)
+	equals(t, int64(2), closes[0].Precision)
+
+	wide, err = quotes.Wide("adjclose", AlignOptions{Join: InnerJoin})
+	ok(t, err)
+	adjusted, err := NewQuotesFromWide(wide, "adjclose")
+	ok(t, err)
+	equals(t, []float64{390}, adjusted[0].AdjClose)
+	equals(t, []float64{0}, adjusted[0].Close)
+
+	_, err = quotes.Wide("trades", AlignOptions{})
+	ok(t, err)
+	_, err = quotes.Wide("bid", AlignOptions{})
+	assert(t, err != nil, "expected an invalid field error")
+	_, err = NewQuotesFromWide(wide, "bid")
+	assert(t, err != nil, "expected an invalid field error")
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewQuoteFromBinanceContext(ctx, "BTCUSDT", "2018-01-01", "2018-02-01", Daily)
+	equals(t, context.Canceled, err)
+	_, err = NewQuotesFromCoinbaseSymsContext(ctx, []string{"BTC-USD", "ETH-USD"}, "2018-01-01", "2018-02-01", Daily)
+	equals(t, context.Canceled, err)
+	_, err = NewEtfListContext(ctx)
+	equals(t, context.Canceled, err)
+}
+
+func TestHTTPConfig(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tiingo/daily/spy/prices":
+			equals(t, "Token secret", r.Header.Get("Authorization"))
+			fmt.Fprint(w, `[{"date":"2018-07-12T00:00:00.000Z","adjOpen":278.28,"adjHigh":279.43,"adjLow":277.6,"adjClose":273.95,"volume":60124700}]`)
+		case "/products":
+			fmt.Fprint(w, `[{"id":"ETH-USD"},{"id":"BTC-USD"}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	saved := HTTP
+	defer func() { HTTP = saved }()
+	HTTP = HTTPConfig{
+		Client:   ts.Client(),
+		BaseURLs: map[string]string{"tiingo": ts.URL, "coinbase": ts.URL + "/"},
+	}
+
+	q, err := NewQuoteFromTiingo("spy", "2018-07-12", "2018-07-13", "secret")
+	ok(t, err)
+	equals(t, 1, len(q.Close))
+	equals(t, 273.95, q.Close[0])
+
+	syms, err := NewMarketList("coinbase")
+	ok(t, err)
+	equals(t, []string{"BTC-USD", "ETH-USD"}, syms)
+}
+
+func TestParseDate(t *testing.T) {
+	ny := time.FixedZone("EST", -5*60*60)
+	now := time.Date(2021, 3, 15, 14, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"2020", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"2020-02", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"2020-2-3", time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"20200203", time.Date(2020, 2, 3, 0, 0, 0, 0, time.UTC)},
+		{"2020-02-03 09:30", time.Date(2020, 2, 3, 9, 30, 0, 0, time.UTC)},
+		{"2020-02-03T09:30:15", time.Date(2020, 2, 3, 9, 30, 15, 0, time.UTC)},
+		{"2020-02-03T09:30:00-05:00", time.Date(2020, 2, 3, 9, 30, 0, 0, ny)},
+		{"2020-02-03 09:30 -0500", time.Date(2020, 2, 3, 9, 30, 0, 0, ny)},
+		{"1580740200", time.Date(2020, 2, 3, 14, 30, 0, 0, time.UTC)},
+		{"1580740200500", time.Date(2020, 2, 3, 14, 30, 0, 500000000, time.UTC)},
+		{"now", now},
+		{"today", time.Date(2021, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.Date(2021, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{"YTD", time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"mtd", time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"-30d", time.Date(2021, 2, 13, 14, 30, 0, 0, time.UTC)},
+		{"-6h", time.Date(2021, 3, 15, 8, 30, 0, 0, time.UTC)},
+		{"+15m", time.Date(2021, 3, 15, 14, 45, 0, 0, time.UTC)},
+		{"-3mo", time.Date(2020, 12, 15, 14, 30, 0, 0, time.UTC)},
+		{"-1y", time.Date(2020, 3, 15, 14, 30, 0, 0, time.UTC)},
+	} {
+		got, err := ParseDateAt(tc.expr, now)
+		ok(t, err)
+		assert(t, got.Equal(tc.want), "%s: expected %v, got %v", tc.expr, tc.want, got)
+	}
+	for _, bad := range []string{"", "2020-13-01", "yesteryear", "-5x", "-d", "2020-02-03 25:00"} {
+		_, err := ParseDateAt(bad, now)
+		assert(t, err != nil, "expected error for '%s'", bad)
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	for _, p := range Periods() {
+		parsed, err := ParsePeriod(p.String())
+		ok(t, err)
+		equals(t, p, parsed)
+		assert(t, p.Duration() > 0, "no duration for %s", p)
+	}
+	p, err := ParsePeriod("3600")
+	ok(t, err)
+	equals(t, Min60, p)
+	equals(t, "1h", p.String())
+	for old, want := range map[string]Period{"60": Min1, "300": Min5, "900": Min15, "1800": Min30} {
+		p, err = ParsePeriod(old)
+		ok(t, err)
+		equals(t, want, p)
+	}
+	p, err = ParsePeriod("1M")
+	ok(t, err)
+	equals(t, Monthly, p)
+	equals(t, 15*time.Minute, Min15.Duration())
+	_, err = ParsePeriod("2m")
+	assert(t, err != nil, "expected error for 2m")
+
+	src, err := NewSource("bittrex", SourceOptions{})
+	ok(t, err)
+	assert(t, SupportsPeriod(src, Min30), "bittrex should support 30m")
+	err = CheckPeriod(src, Min15)
+	assert(t, errors.Is(err, ErrInvalidPeriod), "expected ErrInvalidPeriod, got %v", err)
+
+	// the old spellings still pass the checks and reach the sources
+	ok(t, CheckPeriod(src, Period("1800")))
+	p, err = checkPeriod("coinbase", "", Period("3600"))
+	ok(t, err)
+	equals(t, Min60, p)
+	var interval string
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/klines" {
+			interval = r.URL.Query().Get("interval")
+		}
+		fmt.Fprint(w, `[[1615816800000,"100.0","110.0","90.0","105.0","12.5",1615820399999,"0",1,"0","0","0"]]`)
+	})
+	q, err := c.QuoteFromBinance(context.Background(), "BTCUSDT", "2021-03-15 14:00", "2021-03-15 15:00", Period("3600"))
+	ok(t, err)
+	equals(t, "1h", interval)
+	equals(t, 1, q.Len())
+
+	// coinbase candles come in 1m, 5m, 15m, 1h, 6h and 1d only
+	src, err = NewSource("coinbase", SourceOptions{})
+	ok(t, err)
+	assert(t, SupportsPeriod(src, Hour6), "coinbase should support 6h")
+	for _, p := range []Period{Min30, Weekly} {
+		err = CheckPeriod(src, p)
+		assert(t, errors.Is(err, ErrInvalidPeriod), "expected ErrInvalidPeriod for %s, got %v", p, err)
+		_, err = NewClient().QuoteFromCoinbase(context.Background(), "BTC-USD", "2021-01-01", "2021-01-02", p)
+		assert(t, errors.Is(err, ErrInvalidPeriod), "expected ErrInvalidPeriod for %s, got %v", p, err)
+	}
+
+	results := NewDownloader(stubSource{}, 1).Download(context.Background(), []string{"a", "b"}, "2021-01-01", "", Weekly)
+	assert(t, errors.Is(results[1].Err, ErrInvalidPeriod), "expected ErrInvalidPeriod, got %v", results[1].Err)
+}
+
+func TestRequest(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	ok(t, Request{Symbols: []string{"spy"}, Start: start}.Validate())
+	for _, bad := range []Request{
+		{Start: start},
+		{Symbols: []string{"spy", " "}, Start: start},
+		{Symbols: []string{"spy"}},
+		{Symbols: []string{"spy"}, Start: end, End: start},
+		{Symbols: []string{"spy"}, Start: start, Period: "2m"},
+		{Symbols: []string{"spy"}, Start: start, Limit: -1},
+	} {
+		assert(t, bad.Validate() != nil, "expected error for %+v", bad)
+	}
+
+	r, err := Request{Symbols: []string{"spy"}, End: end, Limit: 10, Period: "3600"}.normalize()
+	ok(t, err)
+	equals(t, Min60, r.Period)
+	equals(t, end.Add(-20*time.Hour), r.Start)
+
+	d := NewDownloader(stubSource{}, 2)
+	d.Limiter = NewRateLimiter(0, 1)
+	results := d.Fetch(context.Background(), Request{Symbols: []string{"aaaa", "bad", "ccc"}, Start: start, Limit: 2})
+	equals(t, 2, results[0].Bars())
+	equals(t, "not found", results[1].Err.Error())
+	equals(t, 2, results[2].Bars())
+
+	results = d.Fetch(context.Background(), Request{Symbols: []string{"a", "b"}})
+	assert(t, results[0].Err != nil && results[1].Err != nil, "expected invalid request to fail every symbol")
+}
+
+func TestClient(t *testing.T) {
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		equals(t, "Token secret", r.Header.Get("Authorization"))
+		fmt.Fprint(w, `[{"date":"2018-07-12T00:00:00.000Z","adjOpen":278.28,"adjHigh":279.43,"adjLow":277.6,"adjClose":273.95,"volume":60124700}]`)
+	})
+	c.TiingoToken = "secret"
+
+	q, err := c.QuoteFromTiingo(context.Background(), "spy", "2018-07-12", "2018-07-13")
+	ok(t, err)
+	equals(t, 273.95, q.AdjClose[0])
+
+	src, err := NewSource("tiingo", SourceOptions{Client: c})
+	ok(t, err)
+	q, err = FetchQuote(context.Background(), src, "spy", "2018-07-12", "2018-07-13", Daily)
+	ok(t, err)
+	equals(t, 1, len(q.Close))
+	_, err = FetchQuote(context.Background(), src, "spy", "2018-07-32", "", Daily)
+	assert(t, err != nil, "expected error for a bad start date")
+
+	// bad dates are reported rather than read as the zero time
+	_, err = c.QuoteFromTiingo(context.Background(), "spy", "2018-07-32", "2018-08-01")
+	assert(t, err != nil, "expected error for a bad start date")
+	_, err = c.QuoteFromBinance(context.Background(), "BTCUSDT", "2018-07-01", "someday", Daily)
+	assert(t, err != nil, "expected error for a bad end date")
+	_, err = NewActionsFromTiingo("spy", "2018-07-32", "", "secret", AllEvents)
+	assert(t, err != nil, "expected error for a bad start date")
+
+	// rate limits aren't shared with the package defaults
+	c.SetRateLimit("tiingo", 5, 1)
+	if c.RateLimit("tiingo") == RateLimit("tiingo") {
+		t.Fatal("client rate limit shared with package")
+	}
+}
+
+type stubSource struct{}
+
+func (s stubSource) Name() string { return "stub" }
+
+func (s stubSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	if err := req.Validate(); err != nil {
+		return Quotes{}, err
+	}
+	quotes := Quotes{}
+	for _, sym := range req.Symbols {
+		if sym == "bad" {
+			return quotes, errors.New("not found")
+		}
+		quotes = append(quotes, req.trim(NewQuote(sym, len(sym))))
+	}
+	return quotes, nil
+}
+
+func (s stubSource) Periods() []Period { return []Period{Daily} }
+
+func (s stubSource) Markets() []string { return nil }
+
+func (s stubSource) MarketList(ctx context.Context, market string) ([]string, error) { return nil, nil }
+
+func TestDownloader(t *testing.T) {
+	d := NewDownloader(stubSource{}, 3)
+	d.Limiter = NewRateLimiter(0, 1)
+	symbols := []string{"a", "bb", "bad", "ccc", "dddd", "eeeee"}
+	results := d.Download(context.Background(), symbols, "2021-01-01", "", Daily)
+	equals(t, len(symbols), len(results))
+	for i, r := range results {
+		equals(t, symbols[i], r.Symbol)
+	}
+	equals(t, 5, len(results.Quotes()))
+	equals(t, "eeeee", results.Quotes()[4].Symbol)
+	equals(t, 1, len(results.Failed()))
+	equals(t, "bad", results.Failed()[0].Symbol)
+	equals(t, 4, results[4].Bars())
+
+	var derr *DownloadError
+	assert(t, errors.As(results.Err(), &derr), "expected *DownloadError, got %v", results.Err())
+	equals(t, 6, derr.Total)
+	equals(t, "1 of 6 symbols failed: bad: not found", derr.Error())
+	assert(t, strings.HasPrefix(results.Summary(), "downloaded 5 of 6 symbols, 15 bars in "), "bad summary: %s", results.Summary())
+	ok(t, results[:2].Err())
+
+	results = d.Download(context.Background(), []string{"a", "b"}, "someday", "", Daily)
+	assert(t, results[0].Err != nil && results[1].Err != nil, "expected a bad start to fail every symbol")
+
+	// the failures are found without following Unwrap to a list, as
+	// errors.Is and errors.As do before Go 1.20
+	derr = &DownloadError{Total: 2, Failed: DownloadResults{
+		{Symbol: "a", Err: errors.New("boom")},
+		{Symbol: "b", Err: newError(ErrSymbolNotFound, "stub", "b", nil)},
+	}}
+	assert(t, derr.Is(ErrSymbolNotFound), "expected ErrSymbolNotFound")
+	assert(t, !derr.Is(ErrRateLimited), "unexpected ErrRateLimited")
+	var qerr *Error
+	assert(t, derr.As(&qerr), "expected an *Error")
+	equals(t, "b", qerr.Symbol)
+	assert(t, errors.Is(derr, ErrSymbolNotFound), "expected errors.Is to find ErrSymbolNotFound")
+}
+
+func TestLogger(t *testing.T) {
+	var b strings.Builder
+	l := NewJSONLogger(&b, LevelInfo)
+	l.Log(LevelDebug, "hidden")
+	l.Log(LevelError, "download failed", F("source", "binance"), F("status", 404), F("elapsed", 1500*time.Millisecond), F("error", errors.New("boom")))
+	line := b.String()
+	assert(t, strings.HasPrefix(line, `{"time":"`), "bad json log: %s", line)
+	assert(t, strings.HasSuffix(line, `"level":"error","msg":"download failed","source":"binance","status":404,"elapsed":"1.5s","error":"boom"}`+"\n"), "bad json log: %s", line)
+
+	b.Reset()
+	NewStdLogger(log.New(&b, "", 0), LevelWarn).Log(LevelWarn, "retrying", F("url", "http://x"), F("wait", time.Second), F("error", errors.New("eof = bad")))
+	equals(t, "warn retrying url=http://x wait=1s error=\"eof = bad\"\n", b.String())
+
+	level, err := ParseLevel("WARN")
+	ok(t, err)
+	equals(t, LevelWarn, level)
+	_, err = ParseLevel("loud")
+	assert(t, err != nil, "expected error for bad level")
+
+	// the download events reach the client's logger
+	var msgs []string
+	c := NewClient()
+	c.Log = LoggerFunc(func(level Level, msg string, fields ...Field) {
+		msgs = append(msgs, level.String()+" "+msg+" "+fields[1].Value.(string))
+	})
+	d := NewDownloader(stubSource{}, 1)
+	d.Client = c
+	d.Limiter = NewRateLimiter(0, 1)
+	d.Download(context.Background(), []string{"a", "bad"}, "2021-01-01", "", Daily)
+	equals(t, []string{"info downloaded a", "error download failed bad"}, msgs)
+}
+
+func TestRateLimiter(t *testing.T) {
+	l := NewRateLimiter(100, 2)
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		ok(t, l.Wait(context.Background()))
+	}
+	elapsed := time.Since(start)
+	assert(t, elapsed >= 35*time.Millisecond, "limiter too fast: %v", elapsed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	equals(t, context.Canceled, NewRateLimiter(0.001, 1).Wait(ctx))
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	c := &Client{Delay: 100 * time.Millisecond, limits: &rateLimits{}}
+	l := c.RateLimit("yahoo")
+	assert(t, c.RateLimit("yahoo") == l, "limiter rebuilt for the same delay")
+	c.Delay = 10 * time.Millisecond
+	assert(t, c.RateLimit("yahoo") != l, "limiter kept after the delay changed")
+
+	c.SetRateLimit("yahoo", 5, 1)
+	set := c.RateLimit("yahoo")
+	c.Delay = time.Second
+	assert(t, c.RateLimit("yahoo") == set, "limiter set explicitly replaced after the delay changed")
+}
+
+func TestRequestRateLimit(t *testing.T) {
+	var mu sync.Mutex
+	var times []time.Time
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if !strings.HasSuffix(r.URL.Path, "/candles") {
+			fmt.Fprint(w, `{"quote_increment":"0.01"}`)
+			return
+		}
+		start, _ := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+		fmt.Fprintf(w, `[[%d,1,2,0.5,1.5,10]]`, start.Unix())
+	}))
+	defer ts.Close()
+
+	c := NewClient()
+	c.HTTP.Client = ts.Client()
+	c.HTTP.BaseURLs = map[string]string{"coinbase": ts.URL}
+	c.SetRateLimit("coinbase", 20, 1)
+	src, err := NewSource("coinbase", SourceOptions{Client: c})
+	ok(t, err)
+
+	// two workers, each paging through 3 windows after a product lookup
+	d := NewDownloader(src, 2)
+	d.Client = c
+	results := d.Fetch(context.Background(), Request{
+		Symbols: []string{"BTC-USD", "ETH-USD"},
+		Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	ok(t, results.Err())
+	equals(t, 8, len(times))
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	for i := 1; i < len(times); i++ {
+		gap := times[i].Sub(times[i-1])
+		assert(t, gap >= 40*time.Millisecond, "requests %d and %d only %v apart", i-1, i, gap)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/Api/v2.0/pub/market/GetTicks":
+			if calls < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"success":true,"result":[{"O":1,"H":2,"L":0.5,"C":1.5,"V":10,"T":"2017-11-28T16:50:00"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	saved := HTTP
+	defer func() { HTTP = saved }()
+	HTTP = HTTPConfig{
+		BaseURLs: map[string]string{"tiingo": ts.URL, "bittrex": ts.URL},
+		Retry:    RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	}
+
+	q, err := NewQuoteFromBittrex("BTC-LTC", Daily)
+	ok(t, err)
+	equals(t, 3, calls)
+	equals(t, []float64{1.5}, q.Close)
+
+	// permanent errors are not retried
+	calls = 0
+	_, err = NewQuoteFromTiingoCrypto("nope", "2018-01-01", "2018-02-01", Daily, "secret")
+	assert(t, err != nil, "expected an error for 404")
+	equals(t, 1, calls)
+
+	// waits longer than MaxBackoff are reported rather than slept
+	calls = 0
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	start := time.Now()
+	_, err = NewQuoteFromBittrex("BTC-LTC", Daily)
+	assert(t, time.Since(start) < time.Second, "waited %v for Retry-After", time.Since(start))
+	equals(t, 1, calls)
+	assert(t, errors.Is(err, ErrRateLimited), "expected rate limited, got %v", err)
+	var qerr *Error
+	assert(t, errors.As(err, &qerr), "expected *Error, got %T", err)
+	equals(t, 24*time.Hour, qerr.RetryAfter)
+	equals(t, "BTC-LTC", qerr.Symbol)
+
+	// connections dropped mid request are retried, failures that can't
+	// go away, such as an unknown scheme, are not
+	calls = 0
+	var retries int
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			ok(t, err)
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"result":[{"O":1,"H":2,"L":0.5,"C":1.5,"V":10,"T":"2017-11-28T16:50:00"}]}`)
+	})
+	c.Log = LoggerFunc(func(level Level, msg string, fields ...Field) {
+		if msg == "retrying" {
+			retries++
+		}
+	})
+	c.HTTP.Retry = RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond}
+	q, err = c.QuoteFromBittrex(context.Background(), "BTC-LTC", Daily)
+	ok(t, err)
+	equals(t, 2, calls)
+	equals(t, 1, retries)
+	retries = 0
+	c.HTTP.BaseURLs["bittrex"] = "gopher://localhost"
+	_, err = c.QuoteFromBittrex(context.Background(), "BTC-LTC", Daily)
+	assert(t, err != nil, "expected an error for an unknown scheme")
+	equals(t, 0, retries)
+}
+
+func TestBackoff(t *testing.T) {
+	// without a MaxBackoff the wait keeps doubling, with jitter of up to half
+	p := RetryPolicy{MinBackoff: 100 * time.Millisecond}
+	for attempt, want := range []time.Duration{100, 200, 400, 800} {
+		want *= time.Millisecond
+		d := p.backoff(attempt)
+		assert(t, d >= want/2 && d <= want, "attempt %d: backoff %v, expected %v to %v", attempt, d, want/2, want)
+	}
+	p.MaxBackoff = 150 * time.Millisecond
+	d := p.backoff(3)
+	assert(t, d >= 75*time.Millisecond && d <= 150*time.Millisecond, "capped backoff %v", d)
+	equals(t, time.Duration(0), RetryPolicy{}.backoff(2))
+}
+
+func TestErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/klines":
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"code":-1121,"msg":"Invalid symbol."}`)
+		case "/tiingo/daily/spy/prices":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/products/BTC-USD/candles":
+			fmt.Fprint(w, `{"message":"oops"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	saved := HTTP
+	defer func() { HTTP = saved }()
+	HTTP = HTTPConfig{BaseURLs: map[string]string{"tiingo": ts.URL, "binance": ts.URL, "coinbase": ts.URL}}
+
+	_, err := NewQuoteFromTiingo("nope", "2018-01-01", "2018-02-01", "secret")
+	assert(t, errors.Is(err, ErrSymbolNotFound), "expected symbol not found, got %v", err)
+	var qerr *Error
+	assert(t, errors.As(err, &qerr), "expected *Error, got %T", err)
+	equals(t, "tiingo", qerr.Source)
+	equals(t, "nope", qerr.Symbol)
+	equals(t, http.StatusNotFound, qerr.Status)
+
+	_, err = NewQuoteFromTiingo("spy", "2018-01-01", "2018-02-01", "bad")
+	assert(t, errors.Is(err, ErrAuthFailed), "expected auth failed, got %v", err)
+
+	_, err = NewQuoteFromBinance("nope", "2018-01-01", "2018-01-02", Daily)
+	assert(t, errors.Is(err, ErrSymbolNotFound), "expected symbol not found, got %v", err)
+
+	_, err = NewQuoteFromCoinbase("BTC-USD", "2018-01-01", "2018-01-02", Daily)
+	assert(t, errors.Is(err, ErrMalformedResponse), "expected malformed response, got %v", err)
+
+	_, err = NewQuoteFromYahoo("spy", "2018-01-01", "2018-01-02", Min1, true)
+	assert(t, errors.Is(err, ErrInvalidPeriod), "expected invalid period, got %v", err)
+
+	// connections that break off or can't be made are network failures
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		fmt.Fprint(w, "[[")
+	})
+	c.TiingoToken = "secret"
+	_, err = c.QuoteFromBinance(context.Background(), "BTCUSDT", "2021-03-15", "2021-03-16", Daily)
+	assert(t, errors.Is(err, ErrNetwork), "expected network failure, got %v", err)
+	assert(t, errors.As(err, &qerr), "expected *Error, got %T", err)
+	equals(t, "binance", qerr.Source)
+	equals(t, "BTCUSDT", qerr.Symbol)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	for name := range c.HTTP.BaseURLs {
+		c.HTTP.BaseURLs[name] = "http://" + l.Addr().String()
+	}
+	l.Close()
+	_, err = c.QuoteFromYahoo(context.Background(), "spy", "2021-03-15", "2021-03-16", Daily, false)
+	assert(t, errors.Is(err, ErrNetwork), "expected network failure, got %v", err)
+	_, err = c.QuoteFromTiingo(context.Background(), "spy", "2021-03-15", "2021-03-16")
+	assert(t, errors.Is(err, ErrNetwork), "expected network failure, got %v", err)
+	_, err = c.QuoteFromCoinbase(context.Background(), "BTC-USD", "2021-03-15", "2021-03-16", Daily)
+	assert(t, errors.Is(err, ErrNetwork), "expected network failure, got %v", err)
+	assert(t, errors.As(err, &qerr), "expected *Error, got %T", err)
+	equals(t, "BTC-USD", qerr.Symbol)
+}
+
+// ftpServer - a one shot anonymous ftp server replying pasv to PASV,
+// serving data on its data connection if pasv is empty
+func ftpServer(t *testing.T, pasv, data string) string {
+	ctrl, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	dl, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	if pasv == "" {
+		port := dl.Addr().(*net.TCPAddr).Port
+		pasv = fmt.Sprintf("227 Entering Passive Mode (127,0,0,1,%d,%d).", port/256, port%256)
+	}
+	go func() {
+		defer ctrl.Close()
+		defer dl.Close()
+		nc, err := ctrl.Accept()
+		if err != nil {
+			return
+		}
+		conn := textproto.NewConn(nc)
+		defer conn.Close()
+		conn.PrintfLine("220 hello")
+		for {
+			line, err := conn.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.Fields(line)[0] {
+			case "USER":
+				conn.PrintfLine("331 password please")
+			case "PASS":
+				conn.PrintfLine("230 ok")
+			case "CWD":
+				conn.PrintfLine("250 ok")
+			case "PASV":
+				conn.PrintfLine("%s", pasv)
+			case "RETR":
+				conn.PrintfLine("150 sending")
+				dc, err := dl.Accept()
+				if err != nil {
+					return
+				}
+				fmt.Fprint(dc, data)
+				dc.Close()
+				conn.PrintfLine("226 done")
+			}
+		}
+	}()
+	return ctrl.Addr().String()
+}
+
+func TestFTP(t *testing.T) {
+	data := "ACT Symbol|Security Name|Exchange|CQS Symbol|ETF|Round Lot Size|Test Issue|NASDAQ Symbol\n" +
+		"SPY|SPDR S&P 500|P|SPY|Y|100|N|SPY\nIBM|IBM|N|IBM|N|100|N|IBM\n"
+	c := NewClient()
+	c.HTTP.BaseURLs = map[string]string{"nasdaq-ftp": ftpServer(t, "", data)}
+	etfs, err := c.EtfList(context.Background())
+	ok(t, err)
+	equals(t, []string{"spy"}, etfs)
+
+	// a malformed PASV reply is an error, not a panic
+	c.HTTP.BaseURLs = map[string]string{"nasdaq-ftp": ftpServer(t, "227 Entering Passive Mode )(", data)}
+	_, err = c.EtfList(context.Background())
+	assert(t, errors.Is(err, ErrMalformedResponse), "expected malformed response, got %v", err)
+	c.HTTP.BaseURLs = map[string]string{"nasdaq-ftp": ftpServer(t, "227 Entering Passive Mode (127,0,0,1,x,1).", data)}
+	_, err = c.EtfList(context.Background())
+	assert(t, errors.Is(err, ErrMalformedResponse), "expected malformed response, got %v", err)
+
+	// as is a refused command
+	c.HTTP.BaseURLs = map[string]string{"nasdaq-ftp": ftpServer(t, "500 no passive mode", data)}
+	_, err = c.EtfList(context.Background())
+	assert(t, errors.Is(err, ErrBadStatus), "expected bad status, got %v", err)
+
+	// and a server that isn't there
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	ok(t, err)
+	c.HTTP.BaseURLs = map[string]string{"nasdaq-ftp": l.Addr().String()}
+	l.Close()
+	_, err = c.EtfList(context.Background())
+	assert(t, errors.Is(err, ErrNetwork), "expected network failure, got %v", err)
+}
@@ -0,0 +1,121 @@
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Request - what to download, accepted by Source.Fetch and
+// Downloader.Fetch. Check it with Validate.
+type Request struct {
+	Symbols []string
+	Start   time.Time // first bar, may be zero if Limit is set
+	End     time.Time // last bar, zero for now
+	Period  Period    // bar size, default Daily
+	Adjust  bool      // adjusted prices as Open..Volume, see Quote.Adjusted
+	Limit   int       // keep only the last Limit bars, 0 for all
+
+	// Options - source specific settings, e.g. "token" for the tiingo
+	// sources. Sources ignore the ones they don't know.
+	Options map[string]string
+}
+
+// Validate - a descriptive error for the first problem in r, nil if
+// it can be downloaded
+func (r Request) Validate() error {
+	_, err := r.normalize()
+	return err
+}
+
+// normalize - r validated with the defaults filled in
+func (r Request) normalize() (Request, error) {
+	if len(r.Symbols) == 0 {
+		return r, errors.New("request has no symbols")
+	}
+	for i, sym := range r.Symbols {
+		if strings.TrimSpace(sym) == "" {
+			return r, fmt.Errorf("request symbol %d is empty", i)
+		}
+	}
+
+	if r.Period == "" {
+		r.Period = Daily
+	} else {
+		p, err := ParsePeriod(string(r.Period))
+		if err != nil {
+			return r, err
+		}
+		r.Period = p
+	}
+
+	if r.Limit < 0 {
+		return r, fmt.Errorf("request limit %d is negative", r.Limit)
+	}
+	if r.End.IsZero() {
+		r.End = time.Now()
+	}
+	if r.Start.IsZero() {
+		if r.Limit == 0 {
+			return r, errors.New("request needs a start time or a limit")
+		}
+		// twice the bars needed, to allow for weekends and holidays
+		r.Start = r.End.Add(-2 * time.Duration(r.Limit) * r.Period.Duration())
+	}
+	if r.End.Before(r.Start) {
+		return r, fmt.Errorf("request end %s is before start %s",
+			r.End.Format("2006-01-02 15:04"), r.Start.Format("2006-01-02 15:04"))
+	}
+	return r, nil
+}
+
+// trim - the bars of q that r asked for
+func (r Request) trim(q Quote) Quote {
+	if r.Limit > 0 {
+		q = q.Last(r.Limit)
+	}
+	return q
+}
+
+// between - the bars of q from r.Start to r.End, for sources that
+// can't be asked for a date range
+func (r Request) between(q Quote) Quote {
+	return q.Between(r.Start, r.End)
+}
+
+// option - the named source specific option, or def if it isn't set
+func (r Request) option(name, def string) string {
+	if v, ok := r.Options[name]; ok {
+		return v
+	}
+	return def
+}
+
+// fetch - download each symbol in req from source with get, which
+// receives the normalized request
+func (c *Client) fetch(ctx context.Context, source string, req Request, get func(ctx context.Context, symbol string, req Request) (Quote, error)) (Quotes, error) {
+	r, err := req.normalize()
+	if err != nil {
+		return Quotes{}, err
+	}
+	if _, err := checkPeriod(source, "", r.Period); err != nil {
+		return Quotes{}, err
+	}
+
+	results, err := c.downloadEach(ctx, source, r.Symbols, func(ctx context.Context, symbol string) (Quote, error) {
+		q, err := get(ctx, symbol, r)
+		if err != nil {
+			return q, err
+		}
+		if r.Adjust {
+			q = q.Adjusted()
+		}
+		return r.trim(q), nil
+	})
+	if err != nil {
+		return results.Quotes(), err
+	}
+	return results.Quotes(), results.Err()
+}
@@ -0,0 +1,196 @@
+package quote
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ResampleOptions - where Resample puts the boundaries of its bars
+type ResampleOptions struct {
+	// Location - time zone of the day, week and month boundaries, nil
+	// for UTC
+	Location *time.Location
+	// SessionStart - start of the trading day after midnight, e.g. 9h30m
+	// for US equities. Days, weeks and months begin then, and intraday
+	// bars are counted from it.
+	SessionStart time.Duration
+	// WeekEnd - last day of a weekly bar, e.g. time.Friday. The zero
+	// value, Sunday, gives weeks starting on Monday.
+	WeekEnd time.Weekday
+	// SessionClose - end of the trading day after midnight, e.g. 16h
+	// for US equities, see ExchangeClose. Close stamped bars of a day or
+	// longer are stamped with the close of their last bar's day, 0 stamps
+	// them with the end of their period.
+	SessionClose time.Duration
+}
+
+// Resample - q aggregated into bars of period to: the first open, the
+// highest high, the lowest low, the last close and the total volume of
+// the bars of q that fall in each. The optional columns are aggregated
+// the same way, counts and volumes summed, and Exact is dropped. Bars
+// keep q's BarTime convention, see ResampleOptions.SessionClose for
+// close stamped days, weeks and months. q's dates must be ascending and
+// its bars no longer than to; resampling to finer bars is an error.
+func Resample(q Quote, to Period, opts ResampleOptions) (Quote, error) {
+	to = canonical(to)
+	if to.Duration() == 0 {
+		return Quote{}, fmt.Errorf("resample %s: unknown period '%s'", q.Symbol, to)
+	}
+	var spacing time.Duration
+	for i := 1; i < q.Len(); i++ {
+		gap := q.Date[i].Sub(q.Date[i-1])
+		if gap <= 0 {
+			return Quote{}, fmt.Errorf("resample %s: dates not ascending at %s", q.Symbol, q.Date[i].Format(csvDateFormat))
+		}
+		if spacing == 0 || gap < spacing {
+			spacing = gap
+		}
+	}
+	if spacing > to.Duration() {
+		return Quote{}, fmt.Errorf("resample %s: bars are %s apart, can't resample to shorter %s bars", q.Symbol, spacing, to)
+	}
+
+	r := NewQuote(q.Symbol, 0)
+	r.Precision = q.Precision
+	r.VolumePrecision = q.VolumePrecision
+	r.BarTime = q.BarTime
+	cols := q.extras()
+	rcols := r.extras()
+	for c, col := range cols {
+		if *col != nil {
+			*rcols[c] = []float64{}
+		}
+	}
+
+	// days, weeks and months close with the session of their last bar
+	session := q.BarTime == BarClose && opts.SessionClose > 0 && to.Duration() >= Daily.Duration()
+	var current time.Time
+	for i := 0; i < q.Len(); i++ {
+		start := bucketStart(q, i, to, opts)
+		n := r.Len() - 1
+		if n < 0 || !start.Equal(current) {
+			current = start
+			d := start
+			if q.BarTime == BarClose {
+				d = barEnd(start, to)
+			}
+			if session {
+				d = sessionClose(q, i, opts)
+			}
+			r.Date = append(r.Date, d.In(q.Date[i].Location()))
+			r.Open = append(r.Open, q.Open[i])
+			r.High = append(r.High, q.High[i])
+			r.Low = append(r.Low, q.Low[i])
+			r.Close = append(r.Close, q.Close[i])
+			r.Volume = append(r.Volume, q.Volume[i])
+			for c, col := range cols {
+				if *col != nil {
+					*rcols[c] = append(*rcols[c], (*col)[i])
+				}
+			}
+			continue
+		}
+		if session {
+			r.Date[n] = sessionClose(q, i, opts).In(q.Date[i].Location())
+		}
+		r.High[n] = math.Max(r.High[n], q.High[i])
+		r.Low[n] = math.Min(r.Low[n], q.Low[i])
+		r.Close[n] = q.Close[i]
+		r.Volume[n] += q.Volume[i]
+		for c, col := range cols {
+			if *col == nil {
+				continue
+			}
+			v := (*col)[i]
+			switch extraColumns[c].name {
+			case "adjopen":
+				// the first, already set
+			case "adjhigh":
+				(*rcols[c])[n] = math.Max((*rcols[c])[n], v)
+			case "adjlow":
+				(*rcols[c])[n] = math.Min((*rcols[c])[n], v)
+			case "adjclose":
+				(*rcols[c])[n] = v
+			default:
+				(*rcols[c])[n] += v
+			}
+		}
+	}
+	return r, nil
+}
+
+// bucketStart - start of the bar of period to that bar i of q falls in
+func bucketStart(q Quote, i int, to Period, opts ResampleOptions) time.Time {
+	t := q.Date[i]
+	if q.BarTime == BarClose {
+		// the bar covers the instant before its close
+		t = t.Add(-time.Nanosecond)
+	}
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	// count from the session start as if it were midnight
+	u := t.In(loc).Add(-opts.SessionStart)
+	day := time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, loc)
+	var start time.Time
+	switch to {
+	case Daily:
+		start = day
+	case Day3:
+		epochDays := time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+		start = day.AddDate(0, 0, -int(epochDays%3))
+	case Weekly:
+		first := (opts.WeekEnd + 1) % 7
+		start = day.AddDate(0, 0, -int((u.Weekday()-first+7)%7))
+	case Monthly:
+		start = time.Date(u.Year(), u.Month(), 1, 0, 0, 0, 0, loc)
+	default:
+		d := to.Duration()
+		start = day.Add(u.Sub(day) / d * d)
+	}
+	return start.Add(opts.SessionStart)
+}
+
+// sessionClose - the session close of the day bar i of q falls in
+func sessionClose(q Quote, i int, opts ResampleOptions) time.Time {
+	return bucketStart(q, i, Daily, opts).Add(opts.SessionClose - opts.SessionStart)
+}
+
+// nests - report whether bars of period from fit a whole number of
+// times in bars of period to, so Resample can build one from the other
+func nests(from, to Period) bool {
+	from, to = canonical(from), canonical(to)
+	f, t := from.Duration(), to.Duration()
+	if f == 0 || t == 0 || f > t {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	day := Daily.Duration()
+	switch to {
+	case Weekly, Monthly, Day3:
+		return f <= day && day%f == 0
+	}
+	return t%f == 0
+}
+
+// BasePeriod - the longest period src downloads that Resample can turn
+// into bars of period to, to itself if src supports it
+func BasePeriod(src Source, to Period) (Period, error) {
+	to = canonical(to)
+	var base Period
+	for _, p := range src.Periods() {
+		if nests(p, to) && p.Duration() > base.Duration() {
+			base = p
+		}
+	}
+	if base == "" {
+		return "", newError(ErrInvalidPeriod, src.Name(), "",
+			fmt.Errorf("'%s' can't be built from %s", to, joinPeriods(src.Periods())))
+	}
+	return base, nil
+}
@@ -0,0 +1,140 @@
+package quote
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy - how failed requests are retried
+type RetryPolicy struct {
+	MaxRetries int           // retries after the first attempt, 0 for none
+	MinBackoff time.Duration // wait before the first retry
+	MaxBackoff time.Duration // upper bound on the wait between retries, 0 for none
+}
+
+// DefaultRetryPolicy - retry policy used unless HTTP.Retry is replaced
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	MinBackoff: 500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// backoff - exponential wait before retry number attempt (0 based),
+// with jitter so parallel downloads don't retry in lock step
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.MinBackoff
+	for i := 0; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryable - transient failures worth another attempt: rate limits
+// and server errors, but not other client errors
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout ||
+		status == 418 || // binance ip ban after ignoring 429s
+		status >= 500
+}
+
+// transient - network failures worth another attempt: timeouts, resets
+// and connections closed early, but not failed dns lookups, bad urls or
+// certificate errors
+func transient(err error) bool {
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE)
+}
+
+// retryAfter - wait requested by the server through Retry-After or the
+// common rate limit reset headers, 0 if none
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t.Sub(now)
+		}
+	}
+	// delta seconds, as in the RateLimit header fields draft
+	if v := resp.Header.Get("RateLimit-Reset"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	// unix time, as used by github and many others
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if epoch, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(epoch, 0).Sub(now)
+		}
+	}
+	return 0
+}
+
+// do - send req for symbol to source, retrying transient failures
+// according to c.HTTP.Retry. Every attempt waits its turn on the source's
+// rate limit. Responses with a permanent error status are returned to the
+// caller, failed connections as an ErrNetwork *Error. A server asking to
+// wait longer than MaxBackoff gets an ErrRateLimited *Error with the wait
+// in RetryAfter instead of a retry.
+func (c *Client) do(req *http.Request, source, symbol string) (*http.Response, error) {
+	ctx := req.Context()
+	client := c.HTTP.client()
+	limiter := c.RateLimit(source)
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := client.Do(req.Clone(ctx))
+		if err == nil {
+			c.log(LevelDebug, "request", F("url", req.URL), F("status", resp.StatusCode), F("elapsed", time.Since(start)))
+		}
+		if ctx.Err() != nil {
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if attempt >= c.HTTP.Retry.MaxRetries || (err == nil && !retryable(resp.StatusCode)) || (err != nil && !transient(err)) {
+			if err != nil {
+				return nil, netErr(ctx, source, symbol, err)
+			}
+			return resp, nil
+		}
+
+		wait := c.HTTP.Retry.backoff(attempt)
+		if err != nil {
+			c.log(LevelWarn, "retrying", F("url", req.URL), F("attempt", attempt+1), F("wait", wait), F("error", err))
+		} else {
+			if ra := retryAfter(resp, time.Now()); ra > wait {
+				if max := c.HTTP.Retry.MaxBackoff; max > 0 && ra > max {
+					resp.Body.Close()
+					return nil, &Error{Kind: ErrRateLimited, Source: source, Symbol: symbol, Status: resp.StatusCode, RetryAfter: ra}
+				}
+				wait = ra
+			}
+			c.log(LevelWarn, "retrying", F("url", req.URL), F("attempt", attempt+1), F("wait", wait), F("status", resp.StatusCode))
+			resp.Body.Close()
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
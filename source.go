@@ -0,0 +1,324 @@
+package quote
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source - a provider of historical price quotes
+type Source interface {
+	// Name - registry name of the source, e.g. "yahoo"
+	Name() string
+	// Fetch - historical prices for the symbols in req
+	Fetch(ctx context.Context, req Request) (Quotes, error)
+	// Periods - the bar periods Fetch accepts
+	Periods() []Period
+	// Markets - names of the markets that MarketList accepts
+	Markets() []string
+	// MarketList - download the list of symbols traded in a market
+	MarketList(ctx context.Context, market string) ([]string, error)
+}
+
+// dateRequest - Request for symbols from startDate to endDate, parsed
+// with ParseDate. An empty endDate is now.
+func dateRequest(symbols []string, startDate, endDate string, period Period) (Request, error) {
+	start, end, err := parseDates(startDate, endDate)
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{Symbols: symbols, Start: start, End: end, Period: period}, nil
+}
+
+// FetchQuote - historical prices for a single symbol from src, the dates
+// in any form ParseDate accepts
+func FetchQuote(ctx context.Context, src Source, symbol, startDate, endDate string, period Period) (Quote, error) {
+	quotes, err := FetchQuotes(ctx, src, []string{symbol}, startDate, endDate, period)
+	return onlyQuote(src, symbol, quotes, err)
+}
+
+// FetchQuotes - historical prices for a list of symbols from src, the
+// dates in any form ParseDate accepts
+func FetchQuotes(ctx context.Context, src Source, symbols []string, startDate, endDate string, period Period) (Quotes, error) {
+	req, err := dateRequest(symbols, startDate, endDate, period)
+	if err != nil {
+		return Quotes{}, err
+	}
+	return src.Fetch(ctx, req)
+}
+
+// SourceOptions - settings used to create a Source
+type SourceOptions struct {
+	Token  string  // api token, for sources that require one
+	Adjust bool    // adjust every Fetch, as Request.Adjust does
+	Client *Client // client used for downloads, nil for the defaults
+}
+
+// client - the client described by opts, with Token applied
+func (opts SourceOptions) client() *Client {
+	c := opts.Client
+	if c == nil {
+		c = defaultClient()
+	}
+	if opts.Token != "" {
+		cc := *c
+		cc.TiingoToken = opts.Token
+		c = &cc
+	}
+	return c
+}
+
+// SourceFactory - creates a Source configured with opts
+type SourceFactory func(opts SourceOptions) Source
+
+var (
+	sourcesMu sync.RWMutex
+	sources   = make(map[string]SourceFactory)
+)
+
+// RegisterSource - make a source available by name, replacing any
+// source previously registered under the same name
+func RegisterSource(name string, factory SourceFactory) {
+	if name == "" || factory == nil {
+		panic("quote: RegisterSource requires a name and a factory")
+	}
+	sourcesMu.Lock()
+	defer sourcesMu.Unlock()
+	sources[name] = factory
+}
+
+// NewSource - create the source registered as name
+func NewSource(name string, opts SourceOptions) (Source, error) {
+	sourcesMu.RLock()
+	factory, ok := sources[name]
+	sourcesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown source '%s', must be one of: %s", name, strings.Join(SourceNames(), ", "))
+	}
+	return factory(opts), nil
+}
+
+// SourceNames - sorted names of all registered sources
+func SourceNames() []string {
+	sourcesMu.RLock()
+	defer sourcesMu.RUnlock()
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	RegisterSource("yahoo", func(opts SourceOptions) Source { return yahooSource{c: opts.client(), adjust: opts.Adjust} })
+	RegisterSource("tiingo", func(opts SourceOptions) Source { return tiingoSource{c: opts.client(), adjust: opts.Adjust} })
+	RegisterSource("tiingo-crypto", func(opts SourceOptions) Source { return tiingoCryptoSource{c: opts.client()} })
+	RegisterSource("coinbase", func(opts SourceOptions) Source { return coinbaseSource{c: opts.client()} })
+	RegisterSource("bittrex", func(opts SourceOptions) Source { return bittrexSource{c: opts.client()} })
+	RegisterSource("binance", func(opts SourceOptions) Source { return binanceSource{c: opts.client()} })
+}
+
+// exchangeZones - time zone of the exchange behind each built in
+// source, the crypto exchanges trade around the clock in UTC
+var exchangeZones = map[string]string{
+	"yahoo":  "America/New_York",
+	"tiingo": "America/New_York",
+}
+
+// exchangeCloses - session close after midnight exchange time of each
+// built in source with daily session bars
+var exchangeCloses = map[string]time.Duration{
+	"yahoo":  16 * time.Hour,
+	"tiingo": 16 * time.Hour,
+}
+
+// ExchangeLocation - time zone of the exchange behind the named source,
+// UTC if it has none
+func ExchangeLocation(source string) (*time.Location, error) {
+	zone, ok := exchangeZones[source]
+	if !ok {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(zone)
+}
+
+// ExchangeClose - session close after midnight exchange time of the
+// named source's daily bars, 0 if it trades around the clock
+func ExchangeClose(source string) time.Duration {
+	return exchangeCloses[source]
+}
+
+// sessionDate - start of the yyyy-mm-dd session date s of source, which
+// is midnight in the exchange time zone, in UTC
+func sessionDate(source, s string) (time.Time, error) {
+	loc, err := ExchangeLocation(source)
+	if err != nil {
+		// no time zone database, dates stay at midnight UTC
+		loc = time.UTC
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, loc)
+	return t.UTC(), err
+}
+
+// marketsWithPrefix - ValidMarkets entries that start with prefix
+func marketsWithPrefix(prefix string) []string {
+	var markets []string
+	for _, m := range ValidMarkets {
+		if strings.HasPrefix(m, prefix) {
+			markets = append(markets, m)
+		}
+	}
+	return markets
+}
+
+// marketList - download a market list on behalf of src
+func marketList(ctx context.Context, c *Client, src Source, market string) ([]string, error) {
+	for _, m := range src.Markets() {
+		if m == market {
+			if market == "etf" {
+				return c.EtfList(ctx)
+			}
+			return c.MarketList(ctx, market)
+		}
+	}
+	return nil, fmt.Errorf("market '%s' not supported by %s", market, src.Name())
+}
+
+type yahooSource struct {
+	c      *Client
+	adjust bool
+}
+
+func (s yahooSource) Name() string { return "yahoo" }
+
+func (s yahooSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	req.Adjust = req.Adjust || s.adjust
+	return s.c.fetch(ctx, "yahoo", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		return s.c.yahoo(ctx, symbol, r.Start, r.End, r.Period)
+	})
+}
+
+func (s yahooSource) Periods() []Period { return sourcePeriods["yahoo"] }
+
+func (s yahooSource) Markets() []string { return []string{"etf"} }
+
+func (s yahooSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
+
+type tiingoSource struct {
+	c      *Client
+	adjust bool
+}
+
+func (s tiingoSource) Name() string { return "tiingo" }
+
+func (s tiingoSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	req.Adjust = req.Adjust || s.adjust
+	c := *s.c
+	c.TiingoToken = req.option("token", c.TiingoToken)
+	return c.fetch(ctx, "tiingo", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		return c.tiingoDaily(ctx, symbol, r.Start, r.End)
+	})
+}
+
+func (s tiingoSource) Periods() []Period { return sourcePeriods["tiingo"] }
+
+func (s tiingoSource) Markets() []string { return []string{"etf"} }
+
+func (s tiingoSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
+
+type tiingoCryptoSource struct {
+	c *Client
+}
+
+func (s tiingoCryptoSource) Name() string { return "tiingo-crypto" }
+
+func (s tiingoCryptoSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	c := *s.c
+	c.TiingoToken = req.option("token", c.TiingoToken)
+	return c.fetch(ctx, "tiingo-crypto", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		return c.tiingoCrypto(ctx, symbol, r.Start, r.End, r.Period)
+	})
+}
+
+func (s tiingoCryptoSource) Periods() []Period { return sourcePeriods["tiingo-crypto"] }
+
+// Markets - none, the tiingo markets are disabled in ValidMarkets
+func (s tiingoCryptoSource) Markets() []string { return nil }
+
+func (s tiingoCryptoSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
+
+type coinbaseSource struct {
+	c *Client
+}
+
+func (s coinbaseSource) Name() string { return "coinbase" }
+
+func (s coinbaseSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	return s.c.fetch(ctx, "coinbase", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		return s.c.coinbase(ctx, symbol, r.Start, r.End, r.Period)
+	})
+}
+
+func (s coinbaseSource) Periods() []Period { return sourcePeriods["coinbase"] }
+
+func (s coinbaseSource) Markets() []string { return marketsWithPrefix("coinbase") }
+
+func (s coinbaseSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
+
+type bittrexSource struct {
+	c *Client
+}
+
+func (s bittrexSource) Name() string { return "bittrex" }
+
+// Fetch - bittrex only serves recent history, the bars outside the
+// requested dates are dropped
+func (s bittrexSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	return s.c.fetch(ctx, "bittrex", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		q, err := s.c.QuoteFromBittrex(ctx, symbol, r.Period)
+		if err != nil {
+			return q, err
+		}
+		return r.between(q), nil
+	})
+}
+
+func (s bittrexSource) Periods() []Period { return sourcePeriods["bittrex"] }
+
+func (s bittrexSource) Markets() []string { return marketsWithPrefix("bittrex") }
+
+func (s bittrexSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
+
+type binanceSource struct {
+	c *Client
+}
+
+func (s binanceSource) Name() string { return "binance" }
+
+func (s binanceSource) Fetch(ctx context.Context, req Request) (Quotes, error) {
+	return s.c.fetch(ctx, "binance", req, func(ctx context.Context, symbol string, r Request) (Quote, error) {
+		return s.c.binance(ctx, symbol, r.Start, r.End, r.Period)
+	})
+}
+
+func (s binanceSource) Periods() []Period { return sourcePeriods["binance"] }
+
+func (s binanceSource) Markets() []string { return marketsWithPrefix("binance") }
+
+func (s binanceSource) MarketList(ctx context.Context, market string) ([]string, error) {
+	return marketList(ctx, s.c, s, market)
+}
@@ -0,0 +1,196 @@
+package quote
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Fields - names of the columns Wide can write: open, high, low, close,
+// volume and the optional columns
+func Fields() []string {
+	fields := []string{"open", "high", "low", "close", "volume"}
+	for _, col := range extraColumns {
+		fields = append(fields, col.name)
+	}
+	return fields
+}
+
+// priceField - index of field in Quote.priceFields, -1 if it isn't
+// one of them
+func priceField(field string) int {
+	for i, f := range Fields()[:5] {
+		if f == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// volumeField - report whether field holds volumes rather than prices
+// or counts
+func volumeField(field string) bool {
+	for _, col := range extraColumns {
+		if col.name == field {
+			return col.volume
+		}
+	}
+	return field == "volume"
+}
+
+// column - the named column of q, nil if it is an optional column q
+// doesn't have, and the decimal places to write it with
+func (q *Quote) column(field string) (*[]float64, int, error) {
+	switch field {
+	case "open":
+		return &q.Open, q.precision(), nil
+	case "high":
+		return &q.High, q.precision(), nil
+	case "low":
+		return &q.Low, q.precision(), nil
+	case "close":
+		return &q.Close, q.precision(), nil
+	case "volume":
+		return &q.Volume, q.volumePrecision(), nil
+	}
+	for i, col := range extraColumns {
+		if col.name == field {
+			return q.extras()[i], q.extraPrecision(i), nil
+		}
+	}
+	return nil, 0, fmt.Errorf("invalid field '%s', must be one of %s", field, strings.Join(Fields(), ", "))
+}
+
+// Wide - field of each Quote in q as a csv table with a row per date
+// and a column per symbol, the quotes aligned by opts. Missing and NaN
+// values are written as empty cells.
+func (q Quotes) Wide(field string, opts AlignOptions) (string, error) {
+	if _, _, err := (&Quote{}).column(field); err != nil {
+		return "", err
+	}
+	aligned, dates, err := q.Align(opts)
+	if err != nil {
+		return "", err
+	}
+
+	var barTime BarTime
+	if len(q) > 0 {
+		barTime = q[0].BarTime
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(csvDateHeader(barTime))
+	for _, quote := range aligned {
+		buffer.WriteString("," + quote.Symbol)
+	}
+	buffer.WriteString("\n")
+
+	for row, d := range dates {
+		buffer.WriteString(d.Format(csvDateFormat))
+		for n := range aligned {
+			buffer.WriteString(",")
+			col, precision, _ := aligned[n].column(field)
+			if *col == nil || math.IsNaN((*col)[row]) {
+				continue
+			}
+			if i := priceField(field); i >= 0 {
+				buffer.WriteString(aligned[n].priceFields(row)[i])
+			} else {
+				buffer.WriteString(strconv.FormatFloat((*col)[row], 'f', precision, 64))
+			}
+		}
+		buffer.WriteString("\n")
+	}
+	return buffer.String(), nil
+}
+
+// WriteWide - write Quotes.Wide to a csv file
+func (q Quotes) WriteWide(filename, field string, opts AlignOptions) error {
+	if filename == "" {
+		filename = "quotes-" + field + ".csv"
+	}
+	csv, err := q.Wide(field, opts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, []byte(csv), 0644)
+}
+
+// NewQuotesFromWide - parse a csv table written by Quotes.Wide into
+// Quotes with field set, one per column. Empty cells are left out, so
+// each Quote has bars only on the dates it had a value. The other
+// prices and the volume are zero.
+func NewQuotesFromWide(csv, field string) (Quotes, error) {
+	if _, _, err := (&Quote{}).column(field); err != nil {
+		return Quotes{}, err
+	}
+	rows := strings.Split(csv, "\n")
+	header := strings.Split(strings.TrimRight(rows[0], "\r"), ",")
+	if len(header) < 2 {
+		return Quotes{}, fmt.Errorf("csv header: expected a date and at least one symbol")
+	}
+
+	quotes := make(Quotes, len(header)-1)
+	for n := range quotes {
+		quotes[n] = NewQuote(header[n+1], 0)
+		quotes[n].BarTime = csvBarTime(rows[0], 0)
+		col, _, _ := quotes[n].column(field)
+		*col = []float64{}
+	}
+	for row := 1; row < len(rows); row++ {
+		if strings.TrimSpace(rows[row]) == "" {
+			continue
+		}
+		line := strings.Split(strings.TrimRight(rows[row], "\r"), ",")
+		if len(line) != len(header) {
+			return Quotes{}, fmt.Errorf("csv row %d: expected %d fields, got %d", row+1, len(header), len(line))
+		}
+		d, err := parseCSVDate("", line[0])
+		if err != nil {
+			return Quotes{}, fmt.Errorf("csv row %d: %w", row+1, err)
+		}
+		for n, cell := range line[1:] {
+			if cell == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				return Quotes{}, fmt.Errorf("csv row %d: %w", row+1, err)
+			}
+			q := &quotes[n]
+			q.Date = append(q.Date, d)
+			q.Open = append(q.Open, 0)
+			q.High = append(q.High, 0)
+			q.Low = append(q.Low, 0)
+			q.Close = append(q.Close, 0)
+			q.Volume = append(q.Volume, 0)
+			col, _, _ := q.column(field)
+			if len(*col) < q.Len() {
+				*col = append(*col, v)
+			} else {
+				(*col)[q.Len()-1] = v
+			}
+			p := csvPrecision([]string{cell})
+			if volumeField(field) {
+				if p > q.VolumePrecision {
+					q.VolumePrecision = p
+				}
+			} else if p > q.Precision {
+				q.Precision = p
+			}
+		}
+	}
+	return quotes, nil
+}
+
+// NewQuotesFromWideFile - parse a csv file written by Quotes.WriteWide
+// into Quotes with field set
+func NewQuotesFromWideFile(filename, field string) (Quotes, error) {
+	csv, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Quotes{}, err
+	}
+	return NewQuotesFromWide(string(csv), field)
+}